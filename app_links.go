@@ -0,0 +1,48 @@
+package seo
+
+import "strings"
+
+// AppLinks app ids and store names of mobile apps, embed it into the site-wide setting struct to edit them in the App Links section,
+// they are rendered as App Links and Smart App Banner tags with deep links of SEO.AppLinks
+type AppLinks struct {
+	IOSAppStoreID  string
+	IOSAppName     string
+	AndroidPackage string
+	AndroidAppName string
+}
+
+// AppLinkConfig deep links of pages, urls could use variables like settings, e.g: myapp://products/{{Code}}
+type AppLinkConfig struct {
+	IOSURL     string
+	AndroidURL string
+}
+
+// appLinksMetadata return App Links tags of the page's deep links, and Smart App Banner of the iOS app.
+// App ids are rendered only with deep links of the platform, except the banner is rendered for all pages
+func (seo *SEO) appLinksMetadata(values settingValues) (metadata OpenGraphProperties) {
+	var config AppLinkConfig
+	if seo.AppLinks != nil {
+		config = *seo.AppLinks
+	}
+
+	if iosURL := fillVariables(config.IOSURL, values.Variables); iosURL != "" {
+		metadata = metadata.addValue("al:ios:url", iosURL).
+			addValue("al:ios:app_store_id", values.SiteWide["IOSAppStoreID"]).
+			addValue("al:ios:app_name", values.SiteWide["IOSAppName"])
+	}
+
+	if androidURL := fillVariables(config.AndroidURL, values.Variables); androidURL != "" {
+		metadata = metadata.addValue("al:android:url", androidURL).
+			addValue("al:android:package", values.SiteWide["AndroidPackage"]).
+			addValue("al:android:app_name", values.SiteWide["AndroidAppName"])
+	}
+
+	if appID := strings.TrimSpace(values.SiteWide["IOSAppStoreID"]); appID != "" {
+		banner := "app-id=" + appID
+		if iosURL := metadata.Get("al:ios:url"); iosURL != "" {
+			banner += ", app-argument=" + iosURL
+		}
+		metadata = metadata.addValue("apple-itunes-app", banner)
+	}
+	return metadata
+}
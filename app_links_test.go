@@ -0,0 +1,73 @@
+package seo
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+type AppGlobalSetting struct {
+	SiteName string
+	AppLinks
+}
+
+func TestAppLinks(t *testing.T) {
+	setupSeoCollection()
+	collection = New("Seo")
+	collection.RegisterGlobalVaribles(&AppGlobalSetting{})
+	collection.RegisterSEO(&SEO{
+		Name:     "ProductPage",
+		Varibles: []string{"Code"},
+		Context: func(objects ...interface{}) map[string]string {
+			return map[string]string{"Code": objects[0].(string)}
+		},
+		AppLinks: &AppLinkConfig{IOSURL: "qor://products/{{Code}}", AndroidURL: "qor://android/products/{{Code}}"},
+	})
+	collection.RegisterSEO(&SEO{Name: "DefaultPage"})
+	Admin = admin.New(&qor.Config{DB: db})
+	Admin.AddResource(collection, &admin.Config{Name: "SEO Setting"})
+	Admin.MountTo("/admin", http.NewServeMux())
+	context := &qor.Context{DB: db}
+
+	db.Create(&QorSEOSetting{Name: "Seo", IsGlobalSEO: true, Setting: Setting{GlobalSetting: map[string]string{
+		"SiteName": "Qor", "IOSAppStoreID": "123", "IOSAppName": "Qor Shop", "AndroidPackage": "com.qor.shop", "AndroidAppName": "Qor Shop",
+	}}})
+	db.Create(&QorSEOSetting{Name: "ProductPage", Setting: Setting{Title: "{{SiteName}}"}})
+	db.Create(&QorSEOSetting{Name: "DefaultPage", Setting: Setting{Title: "{{SiteName}}"}})
+
+	result := string(collection.Render(context, "ProductPage", "sneaker"))
+	expected := strings.Join([]string{
+		`<meta property="al:ios:url" name="al:ios:url" content="qor://products/sneaker">`,
+		`<meta property="al:ios:app_store_id" name="al:ios:app_store_id" content="123">`,
+		`<meta property="al:ios:app_name" name="al:ios:app_name" content="Qor Shop">`,
+		`<meta property="al:android:url" name="al:android:url" content="qor://android/products/sneaker">`,
+		`<meta property="al:android:package" name="al:android:package" content="com.qor.shop">`,
+		`<meta property="al:android:app_name" name="al:android:app_name" content="Qor Shop">`,
+		`<meta property="apple-itunes-app" name="apple-itunes-app" content="app-id=123, app-argument=qor://products/sneaker">`,
+	}, "\n")
+	if !strings.Contains(result, expected) {
+		t.Errorf("app links should be rendered with deep links of the page, but got %v", result)
+	}
+
+	result = string(collection.Render(context, "DefaultPage"))
+	if strings.Contains(result, "al:") || !strings.Contains(result, `apple-itunes-app" content="app-id=123"`) {
+		t.Errorf("only smart app banner should be rendered for pages without deep links, but got %v", result)
+	}
+
+	value := seoGlobalSettingValue(collection, &QorSEOSetting{Setting: Setting{GlobalSetting: map[string]string{"SiteName": "Qor", "IOSAppStoreID": "123"}}})
+	if setting := value.(AppGlobalSetting); setting.SiteName != "Qor" || setting.IOSAppStoreID != "123" {
+		t.Errorf("fields of embedded app links should be edited with site-wide setting, but got %#v", value)
+	}
+
+	var sections []string
+	for _, section := range seoGlobalSettingMetas(collection) {
+		sections = append(sections, section.Title)
+	}
+	if !reflect.DeepEqual(sections, []string{"", "App Links"}) {
+		t.Errorf("app links should be edited in its own section, but got %v", sections)
+	}
+}
@@ -0,0 +1,350 @@
+package seo
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+// AuditIssueType kind of problem found by an audit
+type AuditIssueType string
+
+// Audit issue types
+const (
+	AuditEmptyTitle            AuditIssueType = "empty_title"
+	AuditDuplicateTitle        AuditIssueType = "duplicate_title"
+	AuditDuplicateDescription  AuditIssueType = "duplicate_description"
+	AuditMissingOpenGraphImage AuditIssueType = "missing_open_graph_image"
+	AuditEmptyCustomization    AuditIssueType = "empty_customization"
+)
+
+// auditBatchSize how many records are loaded at once when scanning a resource
+const auditBatchSize = 500
+
+// AuditIssue a problem found in the resolved SEO setting of a record
+type AuditIssue struct {
+	Type     AuditIssueType
+	SEOName  string
+	Resource string
+	RecordID string
+	Value    string
+}
+
+// AuditReport result of auditing all records that have SEO settings
+type AuditReport struct {
+	Records   int
+	Issues    []AuditIssue
+	CreatedAt time.Time
+}
+
+// IssuesByType return issues of given type
+func (report AuditReport) IssuesByType(typ AuditIssueType) (issues []AuditIssue) {
+	for _, issue := range report.Issues {
+		if issue.Type == typ {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// WriteCSV write report's issues as CSV
+func (report AuditReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Type", "SEO", "Resource", "ID", "Value"}); err != nil {
+		return err
+	}
+
+	for _, issue := range report.Issues {
+		if err := writer.Write([]string{string(issue.Type), issue.SEOName, issue.Resource, issue.RecordID, issue.Value}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// seoGetter is implemented by models that embed a Setting field, it tells which registered SEO the record is rendered with
+type seoGetter interface {
+	GetSEO() *SEO
+}
+
+// Audit scan records of all admin resources that have Setting fields, resolve them with GetSEOSetting and report problems.
+// A model is audited when its Setting fields are bound to seos with `seo` tag, or it implements `GetSEO() *SEO`
+func (collection *Collection) Audit(context *qor.Context) (*AuditReport, error) {
+	if collection.resource == nil {
+		return nil, errors.New("seo: collection should be added to admin before auditing")
+	}
+
+	auditor := &seoAuditor{
+		collection:   collection,
+		context:      context,
+		report:       &AuditReport{CreatedAt: time.Now()},
+		titles:       map[string][]AuditIssue{},
+		descriptions: map[string][]AuditIssue{},
+		cache:        newSettingCache(),
+	}
+
+	for _, res := range collection.resource.GetAdmin().GetResources() {
+		if err := auditor.auditResource(res); err != nil {
+			return nil, err
+		}
+	}
+
+	return auditor.finish(), nil
+}
+
+// auditRunner run audits of the admin page in background, the last report is kept and shown until the next audit is finished
+type auditRunner struct {
+	mutex   sync.Mutex
+	running bool
+	report  *AuditReport
+	err     error
+	done    chan struct{}
+}
+
+// start audit the collection in background if it is not running
+func (runner *auditRunner) start(collection *Collection, context *qor.Context) {
+	runner.mutex.Lock()
+	defer runner.mutex.Unlock()
+
+	if runner.running {
+		return
+	}
+	runner.running, runner.done = true, make(chan struct{})
+
+	go func(done chan struct{}) {
+		report, err := collection.Audit(context)
+
+		runner.mutex.Lock()
+		if runner.running, runner.err = false, err; err == nil {
+			runner.report = report
+		}
+		runner.mutex.Unlock()
+		close(done)
+	}(runner.done)
+}
+
+// last return the last report, if an audit is running, and the error of the last audit
+func (runner *auditRunner) last() (*AuditReport, bool, error) {
+	runner.mutex.Lock()
+	defer runner.mutex.Unlock()
+	return runner.report, runner.running, runner.err
+}
+
+// wait wait for the running audit
+func (runner *auditRunner) wait() {
+	runner.mutex.Lock()
+	done := runner.done
+	runner.mutex.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// AuditURL get url of audit page
+func (collection *Collection) AuditURL() string {
+	qorAdmin := collection.resource.GetAdmin()
+	return fmt.Sprintf("%v/%v/!audit", qorAdmin.GetRouter().Prefix, collection.resource.ToParam())
+}
+
+type seoAuditor struct {
+	collection   *Collection
+	context      *qor.Context
+	report       *AuditReport
+	titles       map[string][]AuditIssue
+	descriptions map[string][]AuditIssue
+	// cache page settings and site-wide values are loaded once and resolved for all records
+	cache *settingCache
+}
+
+func (auditor *seoAuditor) auditResource(res *admin.Resource) error {
+	if _, ok := res.Value.(QorSEOSettingInterface); ok || !hasSettingField(res.Value) {
+		return nil
+	}
+
+	if _, ok := res.Value.(seoGetter); !ok && !auditor.hasTaggedField(res.Value) {
+		return nil
+	}
+
+	db := auditor.context.GetDB()
+	scope := db.NewScope(res.Value)
+	order := scope.Quote(scope.PrimaryKey())
+
+	for offset := 0; ; offset += auditBatchSize {
+		records := res.NewSlice()
+		if err := db.Order(order).Offset(offset).Limit(auditBatchSize).Find(records).Error; err != nil {
+			return err
+		}
+
+		values := reflect.Indirect(reflect.ValueOf(records))
+		for i := 0; i < values.Len(); i++ {
+			if err := auditor.auditRecord(res, values.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+
+		if values.Len() < auditBatchSize {
+			return nil
+		}
+	}
+}
+
+// auditRecord audit Setting fields of a record, tagged fields with their seo, and the first untagged field with GetSEO of the record
+func (auditor *seoAuditor) auditRecord(res *admin.Resource, record interface{}) error {
+	getter, ok := record.(seoGetter)
+	if !ok {
+		ptr := reflect.New(reflect.TypeOf(record))
+		ptr.Elem().Set(reflect.ValueOf(record))
+		getter, _ = ptr.Interface().(seoGetter)
+	}
+
+	var audited, untaggedAudited bool
+	for _, field := range settingFieldsOf(record) {
+		var seo *SEO
+		if field.SEO != "" {
+			if auditor.collection.isRegistered(field.SEO) {
+				seo = auditor.collection.GetSEO(field.SEO)
+			}
+		} else if getter != nil && !untaggedAudited {
+			untaggedAudited = true
+			seo = getter.GetSEO()
+		}
+
+		if seo == nil || seo.collection != auditor.collection {
+			continue
+		}
+
+		if !audited {
+			audited = true
+			auditor.report.Records++
+		}
+
+		if err := auditor.auditSetting(res, record, seo, field.Setting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (auditor *seoAuditor) auditSetting(res *admin.Resource, record interface{}, seo *SEO, raw Setting) error {
+	issue := AuditIssue{
+		SEOName:  seo.Name,
+		Resource: res.Name,
+		RecordID: fmt.Sprint(auditor.context.GetDB().NewScope(record).PrimaryKeyValue()),
+	}
+
+	add := func(typ AuditIssueType, value string) {
+		issue.Type = typ
+		issue.Value = value
+		auditor.report.Issues = append(auditor.report.Issues, issue)
+	}
+
+	if raw.isEmptyCustomization() {
+		add(AuditEmptyCustomization, "")
+	}
+
+	// settings not saved yet are reported as empty titles
+	setting, _, err := auditor.collection.getSEOSetting(auditor.context, auditor.cache, seo.Name, record)
+	if _, ok := err.(*SettingNotFoundError); err != nil && !ok {
+		return err
+	}
+
+	if title := strings.TrimSpace(setting.Title); title == "" {
+		add(AuditEmptyTitle, "")
+	} else {
+		auditor.titles[title] = append(auditor.titles[title], issue)
+	}
+
+	if description := strings.TrimSpace(setting.Description); description != "" {
+		auditor.descriptions[description] = append(auditor.descriptions[description], issue)
+	}
+
+	// pages with a generator always have an image, generated from their titles
+	if !setting.hasOpenGraphImage() && (seo.OpenGraph == nil || seo.OpenGraph.Generator == nil) {
+		add(AuditMissingOpenGraphImage, "")
+	}
+	return nil
+}
+
+func (auditor *seoAuditor) finish() *AuditReport {
+	report := auditor.report
+
+	addDuplicates := func(typ AuditIssueType, groups map[string][]AuditIssue) {
+		for value, issues := range groups {
+			if len(issues) > 1 {
+				for _, issue := range issues {
+					issue.Type = typ
+					issue.Value = value
+					report.Issues = append(report.Issues, issue)
+				}
+			}
+		}
+	}
+	addDuplicates(AuditDuplicateTitle, auditor.titles)
+	addDuplicates(AuditDuplicateDescription, auditor.descriptions)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.RecordID < b.RecordID
+	})
+
+	return report
+}
+
+// hasSettingField check if a model has a Setting field
+func hasSettingField(value interface{}) bool {
+	typ := reflect.Indirect(reflect.ValueOf(value)).Type()
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Type == reflect.TypeOf(Setting{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTaggedField check if a model has Setting fields bound to seos of the collection with `seo` tag
+func (auditor *seoAuditor) hasTaggedField(value interface{}) bool {
+	for _, field := range settingFieldsOf(value) {
+		if field.SEO != "" && auditor.collection.isRegistered(field.SEO) {
+			return true
+		}
+	}
+	return false
+}
+
+func (setting Setting) hasOpenGraphImage() bool {
+	if len(setting.OpenGraphImageFromMediaLibrary.Files) > 0 || setting.OpenGraphImageURL != "" {
+		return true
+	}
+
+	for _, metadata := range setting.OpenGraphMetadata {
+		if metadata.Property == "og:image" && metadata.Content != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package seo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/qor"
+)
+
+type AuditProduct struct {
+	gorm.Model
+	Name string
+	SEO  Setting
+}
+
+func (AuditProduct) GetSEO() *SEO {
+	return collection.GetSEO("CategoryPage")
+}
+
+func TestAudit(t *testing.T) {
+	setupSeoCollection()
+	db.DropTableIfExists(&AuditProduct{})
+	db.AutoMigrate(&AuditProduct{})
+	Admin.AddResource(&AuditProduct{})
+
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} Category", Description: "Category"})
+
+	db.Create(&AuditProduct{Name: "Default 1"})
+	db.Create(&AuditProduct{Name: "Default 2"})
+	db.Create(&AuditProduct{Name: "Customized", SEO: Setting{Title: "Unique", Description: "Unique", OpenGraphImageURL: "/image.jpg", EnabledCustomize: true}})
+	db.Create(&AuditProduct{Name: "Empty", SEO: Setting{EnabledCustomize: true}})
+
+	var settingQueries int
+	db.Callback().Query().After("gorm:query").Register("seo_test:count_setting_queries", func(scope *gorm.Scope) {
+		if scope.TableName() == scope.New(&QorSEOSetting{}).TableName() {
+			settingQueries++
+		}
+	})
+	report, err := collection.Audit(&qor.Context{DB: db})
+	db.Callback().Query().Remove("seo_test:count_setting_queries")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if settingQueries != 2 {
+		t.Errorf("page setting and site-wide setting should be loaded once, but got %v queries", settingQueries)
+	}
+
+	if report.Records != 4 {
+		t.Errorf("should audit 4 records, but got %v", report.Records)
+	}
+
+	if issues := report.IssuesByType(AuditDuplicateTitle); len(issues) != 2 || issues[0].Value != "Qor Category" {
+		t.Errorf("should find duplicate titles of default records, but got %#v", issues)
+	}
+
+	if issues := report.IssuesByType(AuditDuplicateDescription); len(issues) != 2 {
+		t.Errorf("should find duplicate descriptions of default records, but got %#v", issues)
+	}
+
+	if issues := report.IssuesByType(AuditEmptyCustomization); len(issues) != 1 || issues[0].RecordID != "4" {
+		t.Errorf("should find empty customization, but got %#v", issues)
+	}
+
+	if issues := report.IssuesByType(AuditEmptyTitle); len(issues) != 1 || issues[0].RecordID != "4" {
+		t.Errorf("should find empty title, but got %#v", issues)
+	}
+
+	if issues := report.IssuesByType(AuditMissingOpenGraphImage); len(issues) != 3 {
+		t.Errorf("should find records without open graph image, but got %#v", issues)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(report.Issues)+1 || lines[0] != "Type,SEO,Resource,ID,Value" {
+		t.Errorf("csv export is not correct, got %v", buf.String())
+	}
+}
+
+func TestAuditRunner(t *testing.T) {
+	setupSeoCollection()
+	db.DropTableIfExists(&AuditProduct{})
+	db.AutoMigrate(&AuditProduct{})
+	Admin.AddResource(&AuditProduct{})
+	createCategoryPageSetting(Setting{Title: "Category"})
+	db.Create(&AuditProduct{Name: "Default"})
+
+	runner := collection.auditRunner
+	if report, running, err := runner.last(); report != nil || running || err != nil {
+		t.Errorf("audit should not be run before started, but got %v %v %v", report, running, err)
+	}
+
+	runner.start(collection, &qor.Context{DB: db})
+	runner.wait()
+	report, running, err := runner.last()
+	if err != nil || running || report == nil || len(report.IssuesByType(AuditMissingOpenGraphImage)) != 1 {
+		t.Fatalf("report of the audit run in background should be kept, but got %#v %v %v", report, running, err)
+	}
+
+	collection.GetSEO("CategoryPage").OpenGraph.Generator = &OpenGraphImageGenerator{Prefix: "/og-images/"}
+	runner.start(collection, &qor.Context{DB: db})
+	runner.wait()
+	if report, _, _ := runner.last(); len(report.IssuesByType(AuditMissingOpenGraphImage)) != 0 {
+		t.Errorf("pages with generated images should not miss open graph images, but got %v", report.Issues)
+	}
+}
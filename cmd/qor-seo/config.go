@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+	"github.com/qor/seo"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describe the seo collection of an application, as seo registration is done in go code of the application,
+// it need to be declared again for qor-seo. config files could be written in yaml or json
+type Config struct {
+	// Collection name of the seo collection, that's also the name of site-wide setting
+	Collection      string      `yaml:"collection"`
+	GlobalVariables []string    `yaml:"global_variables"`
+	SEOs            []SEOConfig `yaml:"seos"`
+}
+
+// SEOConfig a registered seo
+type SEOConfig struct {
+	Name      string         `yaml:"name"`
+	Variables []string       `yaml:"variables"`
+	Sitemap   *SitemapConfig `yaml:"sitemap"`
+}
+
+// SitemapConfig sitemap source of a seo, urls could be static or generated from records of a table
+type SitemapConfig struct {
+	URLs []string `yaml:"urls"`
+
+	// Table records of the table will be added to sitemap, Where is an optional sql condition to filter records
+	Table string `yaml:"table"`
+	Where string `yaml:"where"`
+	// Path url path of records, columns could be used as variables, e.g: /products/{{code}}
+	Path string `yaml:"path"`
+	// LastMod column of records' last modified time
+	LastMod string `yaml:"lastmod"`
+
+	ChangeFreq string  `yaml:"changefreq"`
+	Priority   float64 `yaml:"priority"`
+}
+
+var columnRegexp = regexp.MustCompile("{{([a-zA-Z0-9_]+)}}")
+
+func loadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config %v: %v", path, err)
+	}
+
+	if config.Collection == "" {
+		return nil, fmt.Errorf("collection name is required in config %v", path)
+	}
+	return config, nil
+}
+
+// newCollection build a seo collection from config, it is not configured for admin,
+// but it is enough for managing settings in the database
+func newCollection(config *Config, db *gorm.DB) (*seo.Collection, error) {
+	collection := seo.New(config.Collection)
+	collection.SettingResource = admin.New(&qor.Config{DB: db}).NewResource(&seo.QorSEOSetting{})
+
+	if len(config.GlobalVariables) > 0 {
+		var fields []reflect.StructField
+		for _, name := range config.GlobalVariables {
+			if !token.IsIdentifier(name) || !token.IsExported(name) {
+				return nil, fmt.Errorf("global variable %v should be an exported go identifier", name)
+			}
+			fields = append(fields, reflect.StructField{Name: name, Type: reflect.TypeOf("")})
+		}
+		collection.RegisterGlobalVaribles(reflect.New(reflect.StructOf(fields)).Interface())
+	}
+
+	for _, seoConfig := range config.SEOs {
+		s := &seo.SEO{Name: seoConfig.Name, Varibles: seoConfig.Variables}
+		if seoConfig.Sitemap != nil {
+			s.Sitemap = seoConfig.Sitemap.urls
+		}
+		collection.RegisterSEO(s)
+	}
+	return collection, nil
+}
+
+// urls return static urls, and urls of records if table is configured
+func (config *SitemapConfig) urls(context *qor.Context) (urls []seo.SitemapURL, err error) {
+	for _, loc := range config.URLs {
+		urls = append(urls, seo.SitemapURL{Loc: loc, ChangeFreq: config.ChangeFreq, Priority: config.Priority})
+	}
+
+	if config.Table == "" {
+		return urls, nil
+	}
+
+	scope := context.GetDB().Table(config.Table)
+	if config.Where != "" {
+		scope = scope.Where(config.Where)
+	}
+
+	rows, err := scope.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		record := map[string]interface{}{}
+		for i, column := range columns {
+			record[column] = values[i]
+		}
+
+		u := seo.SitemapURL{ChangeFreq: config.ChangeFreq, Priority: config.Priority}
+		u.Loc = columnRegexp.ReplaceAllStringFunc(config.Path, func(match string) string {
+			return columnString(record[columnRegexp.FindStringSubmatch(match)[1]])
+		})
+
+		if config.LastMod != "" {
+			if lastMod, ok := columnTime(record[config.LastMod]); ok {
+				u.LastMod = &lastMod
+			}
+		}
+		urls = append(urls, u)
+	}
+	return urls, rows.Err()
+}
+
+func columnString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func columnTime(value interface{}) (time.Time, bool) {
+	if t, ok := value.(time.Time); ok {
+		return t, true
+	}
+
+	str := columnString(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,394 @@
+// Command qor-seo manages seo settings in the database of a qor application without the admin interface.
+//
+// Usage:
+//
+//	qor-seo [-config qor-seo.yml] [-dialect mysql] [-dsn DSN] <command> [arguments]
+//
+// Commands:
+//
+//	list                                   list registered seos and their saved settings
+//	dump [-format json|yaml|csv] [-o file] dump settings
+//	load [-dry-run] [-conflict overwrite|skip|fail] file
+//	                                       load settings from a json, yaml or csv file
+//	lint                                   check saved settings of the configured collection, exit with status 1 if there are errors
+//	render [-var Name=Value]... name       render a seo with sample variables
+//	sync                                   create missing settings and report orphaned settings
+//	sitemap -base URL [-o dir]             generate sitemaps to disk
+//
+// The database could also be set with environment variables QOR_SEO_DB_DIALECT and QOR_SEO_DB_DSN.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/qor/qor"
+	"github.com/qor/seo"
+	"gopkg.in/yaml.v3"
+)
+
+type command struct {
+	collection *seo.Collection
+	config     *Config
+	context    *qor.Context
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+var commands = map[string]func(*command, []string) error{
+	"list":    (*command).list,
+	"dump":    (*command).dump,
+	"load":    (*command).load,
+	"lint":    (*command).lint,
+	"render":  (*command).render,
+	"sitemap": (*command).sitemap,
+	"sync":    (*command).sync,
+}
+
+func main() {
+	var (
+		configPath = flag.String("config", "qor-seo.yml", "config file of registered seos")
+		dialect    = flag.String("dialect", envOr("QOR_SEO_DB_DIALECT", "mysql"), "database dialect, mysql or postgres")
+		dsn        = flag.String("dsn", os.Getenv("QOR_SEO_DB_DSN"), "database connection string")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: qor-seo [flags] list|dump|load|lint|render|sitemap|sync [arguments]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	run, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		exit(err)
+	}
+
+	db, err := gorm.Open(*dialect, *dsn)
+	if err != nil {
+		exit(err)
+	}
+	defer db.Close()
+	// keep stdout for dumped settings
+	db.SetLogger(gorm.Logger{LogWriter: log.New(os.Stderr, "\r\n", 0)})
+
+	collection, err := newCollection(config, db)
+	if err != nil {
+		exit(err)
+	}
+
+	cmd := &command{collection: collection, config: config, context: &qor.Context{DB: db}, stdout: os.Stdout, stderr: os.Stderr}
+	if err := run(cmd, flag.Args()[1:]); err != nil {
+		db.Close()
+		exit(err)
+	}
+}
+
+func (cmd *command) list(args []string) error {
+	settings, err := cmd.collection.Export(cmd.context)
+	if err != nil {
+		return err
+	}
+
+	saved := map[string]seo.Setting{}
+	for _, setting := range settings {
+		saved[setting.Name] = setting.Setting
+	}
+
+	writer := tabwriter.NewWriter(cmd.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tVARIABLES\tSAVED\tTITLE")
+
+	_, ok := saved[cmd.config.Collection]
+	fmt.Fprintf(writer, "%v\t%v\t%v\t\n", cmd.config.Collection, strings.Join(cmd.config.GlobalVariables, ","), ok)
+	for _, s := range cmd.config.SEOs {
+		setting, ok := saved[s.Name]
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\n", s.Name, strings.Join(s.Variables, ","), ok, setting.Title)
+	}
+	return writer.Flush()
+}
+
+func (cmd *command) dump(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	format := flags.String("format", "", "json, yaml or csv, default is the extension of output file, or json")
+	output := flags.String("o", "", "output file, default is stdout")
+	flags.Parse(args)
+
+	settings, err := cmd.collection.Export(cmd.context)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return writeSettings(w, settings, fileFormat(*format, *output))
+}
+
+func (cmd *command) load(args []string) error {
+	flags := flag.NewFlagSet("load", flag.ExitOnError)
+	format := flags.String("format", "", "json, yaml or csv, default is the extension of the file")
+	dryRun := flags.Bool("dry-run", false, "only validate and print changes, nothing will be saved")
+	conflict := flags.String("conflict", string(seo.ImportOverwrite), "how to handle settings different from existing ones, overwrite, skip or fail")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: qor-seo load [-dry-run] [-conflict overwrite|skip|fail] file")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	settings, err := readSettings(file, fileFormat(*format, file.Name()))
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.context.GetDB().AutoMigrate(&seo.QorSEOSetting{}, &seo.QorSEOSettingVersion{}).Error; err != nil {
+		return err
+	}
+
+	results, err := cmd.collection.Import(cmd.context, settings, seo.ImportOptions{DryRun: *dryRun, Conflict: seo.ImportConflict(*conflict)})
+	cmd.printImportResults(results)
+	return err
+}
+
+func (cmd *command) lint(args []string) error {
+	// settings table may be shared with other collections, only lint settings of the configured one
+	names := []string{cmd.config.Collection}
+	for _, s := range cmd.config.SEOs {
+		names = append(names, s.Name)
+	}
+
+	var records []seo.QorSEOSetting
+	if err := cmd.context.GetDB().Where("name IN (?)", names).Find(&records).Error; err != nil {
+		return err
+	}
+
+	var (
+		settings []seo.ExportedSetting
+		saved    = map[string]bool{}
+		failed   bool
+	)
+	for _, record := range records {
+		saved[record.Name] = true
+		settings = append(settings, seo.ExportedSetting{Name: record.Name, IsGlobalSEO: record.IsGlobalSEO, Setting: record.Setting})
+		if !record.IsGlobalSEO && strings.TrimSpace(record.Setting.Title) == "" {
+			failed = true
+			fmt.Fprintf(cmd.stdout, "%v: title is empty\n", record.Name)
+		}
+	}
+
+	for _, s := range cmd.config.SEOs {
+		if !saved[s.Name] {
+			fmt.Fprintf(cmd.stdout, "%v: warning: setting is not saved\n", s.Name)
+		}
+	}
+
+	results, err := cmd.collection.Import(cmd.context, settings, seo.ImportOptions{DryRun: true})
+	for _, result := range results {
+		for _, e := range result.Errors {
+			fmt.Fprintf(cmd.stdout, "%v: %v\n", result.Name, e)
+		}
+	}
+
+	if err != nil && err != seo.ErrImportFailed {
+		return err
+	}
+	if failed || err != nil {
+		return errors.New("lint failed")
+	}
+	return nil
+}
+
+func (cmd *command) render(args []string) error {
+	variables := variablesFlag{}
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	flags.Var(variables, "var", "sample variable as Name=Value, could be used multiple times")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: qor-seo render [-var Name=Value]... name")
+	}
+
+	name := flags.Arg(0)
+	if !cmd.isRegistered(name) {
+		return fmt.Errorf("seo %v is not registered", name)
+	}
+
+	cmd.collection.GetSEO(name).Context = func(...interface{}) map[string]string { return variables }
+	result, err := cmd.collection.RenderE(cmd.context, name)
+	if _, ok := err.(*seo.SettingNotFoundError); ok {
+		// tags of global variables are still rendered, like Render does for pages without saved setting
+		fmt.Fprintf(cmd.stderr, "%v: warning: %v\n", name, err)
+	} else if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.stdout, result)
+	return err
+}
+
+func (cmd *command) sitemap(args []string) error {
+	flags := flag.NewFlagSet("sitemap", flag.ExitOnError)
+	baseURL := flags.String("base", "", "base url of relative urls, e.g: https://example.com")
+	dir := flags.String("o", ".", "output directory")
+	flags.Parse(args)
+
+	if *baseURL == "" {
+		return errors.New("usage: qor-seo sitemap -base URL [-o dir]")
+	}
+
+	if err := os.MkdirAll(*dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	files, err := cmd.collection.WriteSitemaps(cmd.context, *dir, *baseURL)
+	for _, file := range files {
+		fmt.Fprintln(cmd.stdout, file)
+	}
+	return err
+}
+
+func (cmd *command) sync(args []string) error {
+	db := cmd.context.GetDB()
+	if err := db.AutoMigrate(&seo.QorSEOSetting{}, &seo.QorSEOSettingVersion{}).Error; err != nil {
+		return err
+	}
+
+	report, err := cmd.collection.Sync(db)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range report.Created {
+		fmt.Fprintf(cmd.stdout, "created\t%v\n", name)
+	}
+	for _, name := range report.Orphaned {
+		fmt.Fprintf(cmd.stdout, "orphaned\t%v\n", name)
+	}
+	return nil
+}
+
+func (cmd *command) isRegistered(name string) bool {
+	for _, s := range cmd.config.SEOs {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmd *command) printImportResults(results []seo.ImportResult) {
+	for _, result := range results {
+		fmt.Fprintf(cmd.stdout, "%v: %v\n", result.Name, result.Action)
+		for _, change := range result.Changes {
+			fmt.Fprintf(cmd.stdout, "  %v: %q -> %q\n", change.Field, change.Before, change.After)
+		}
+		for _, e := range result.Errors {
+			fmt.Fprintf(cmd.stdout, "  error: %v\n", e)
+		}
+	}
+}
+
+// variablesFlag sample variables of render command
+type variablesFlag map[string]string
+
+func (variables variablesFlag) String() string {
+	var pairs []string
+	for key, value := range variables {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (variables variablesFlag) Set(value string) error {
+	pair := strings.SplitN(value, "=", 2)
+	if len(pair) != 2 {
+		return fmt.Errorf("variable %v should be Name=Value", value)
+	}
+	variables[pair[0]] = pair[1]
+	return nil
+}
+
+// fileFormat return format, or extension of the file if format is blank
+func fileFormat(format string, file string) string {
+	if format == "" {
+		if format = strings.TrimPrefix(filepath.Ext(file), "."); format == "" {
+			format = "json"
+		}
+	}
+	if format == "yml" {
+		format = "yaml"
+	}
+	return format
+}
+
+// writeSettings write settings in yaml with the same structure as json, or in formats supported by seo
+func writeSettings(w io.Writer, settings []seo.ExportedSetting, format string) error {
+	if format != "yaml" {
+		return seo.WriteSettings(w, settings, format)
+	}
+
+	content, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal(content, &value); err != nil {
+		return err
+	}
+	return yaml.NewEncoder(w).Encode(value)
+}
+
+func readSettings(r io.Reader, format string) (settings []seo.ExportedSetting, err error) {
+	if format != "yaml" {
+		return seo.ReadSettings(r, format)
+	}
+
+	var value interface{}
+	if err := yaml.NewDecoder(r).Decode(&value); err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return settings, json.Unmarshal(content, &settings)
+}
+
+func envOr(key string, value string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return value
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, "qor-seo:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/qor"
+	"github.com/qor/qor/test/utils"
+	"github.com/qor/seo"
+)
+
+type Product struct {
+	gorm.Model
+	Code string
+}
+
+var db = utils.TestDB()
+
+func newTestCommand(t *testing.T) (cmd *command, stdout *bytes.Buffer, stderr *bytes.Buffer) {
+	db.DropTableIfExists(&seo.QorSEOSetting{}, &seo.QorSEOSettingVersion{}, &Product{})
+	db.AutoMigrate(&seo.QorSEOSetting{}, &seo.QorSEOSettingVersion{}, &Product{})
+
+	config := &Config{
+		Collection:      "Seo",
+		GlobalVariables: []string{"SiteName"},
+		SEOs: []SEOConfig{
+			{Name: "DefaultPage", Sitemap: &SitemapConfig{URLs: []string{"/"}}},
+			{Name: "ProductPage", Variables: []string{"Code"}, Sitemap: &SitemapConfig{Table: "products", Path: "/products/{{code}}"}},
+		},
+	}
+
+	collection, err := newCollection(config, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	return &command{collection: collection, config: config, context: &qor.Context{DB: db}, stdout: stdout, stderr: stderr}, stdout, stderr
+}
+
+func TestDumpAndLoad(t *testing.T) {
+	cmd, _, _ := newTestCommand(t)
+	db.Create(&seo.QorSEOSetting{Name: "Seo", IsGlobalSEO: true, Setting: seo.Setting{GlobalSetting: map[string]string{"SiteName": "Qor"}}})
+	db.Create(&seo.QorSEOSetting{Name: "ProductPage", Setting: seo.Setting{Title: "{{Code}} - {{SiteName}}", Description: "product {{Code}}"}})
+
+	file := filepath.Join(t.TempDir(), "settings.yml")
+	if err := cmd.dump([]string{"-o", file}); err != nil {
+		t.Fatal(err)
+	}
+
+	dumped, err := cmd.collection.Export(cmd.context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Unscoped().Delete(&seo.QorSEOSetting{})
+	if err := cmd.load([]string{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := cmd.collection.Export(cmd.context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded) != 2 || !reflect.DeepEqual(loaded, dumped) {
+		t.Errorf("loaded settings should be the same as dumped ones, expect %#v, but got %#v", dumped, loaded)
+	}
+}
+
+func TestLint(t *testing.T) {
+	cmd, stdout, _ := newTestCommand(t)
+	db.Create(&seo.QorSEOSetting{Name: "DefaultPage", Setting: seo.Setting{Title: "{{SiteName}}"}})
+	db.Create(&seo.QorSEOSetting{Name: "ProductPage", Setting: seo.Setting{Description: "{{Code}}"}})
+	db.Create(&seo.QorSEOSetting{Name: "BlogPage", Setting: seo.Setting{Description: "setting of other collections"}})
+
+	if err := cmd.lint(nil); err == nil || !strings.Contains(stdout.String(), "ProductPage: title is empty") {
+		t.Errorf("lint should fail for settings without title, but got %v, %v", err, stdout.String())
+	}
+
+	db.Model(&seo.QorSEOSetting{}).Where("name = ?", "ProductPage").Update("setting", seo.Setting{Title: "{{Code}}"})
+	if err := cmd.lint(nil); err != nil {
+		t.Errorf("lint should pass for valid settings, but got %v", err)
+	}
+
+	if strings.Contains(stdout.String(), "BlogPage") {
+		t.Errorf("settings of other collections should not be linted, but got %v", stdout.String())
+	}
+}
+
+func TestRender(t *testing.T) {
+	cmd, stdout, stderr := newTestCommand(t)
+	db.Create(&seo.QorSEOSetting{Name: "Seo", IsGlobalSEO: true, Setting: seo.Setting{GlobalSetting: map[string]string{"SiteName": "Qor"}}})
+	db.Create(&seo.QorSEOSetting{Name: "ProductPage", Setting: seo.Setting{Title: "{{Code}} - {{SiteName}}"}})
+
+	if err := cmd.render([]string{"-var", "Code=sneaker", "ProductPage"}); err != nil || !strings.Contains(stdout.String(), "<title>sneaker - Qor</title>") {
+		t.Errorf("seo should be rendered with sample variables, but got %v, %v", err, stdout.String())
+	}
+
+	stdout.Reset()
+	if err := cmd.render([]string{"DefaultPage"}); err != nil || !strings.Contains(stdout.String(), "<title>") {
+		t.Errorf("seo without saved setting should still be rendered, but got %v, %v", err, stdout.String())
+	}
+
+	if !strings.Contains(stderr.String(), "DefaultPage: warning:") {
+		t.Errorf("missing setting should be reported as a warning, but got %v", stderr.String())
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	cmd, stdout, _ := newTestCommand(t)
+	db.Create(&Product{Code: "sneaker"})
+	db.Create(&Product{Code: "boot"})
+
+	dir := t.TempDir()
+	if err := cmd.sitemap([]string{"-base", "https://example.com", "-o", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "sitemap.xml")
+	if strings.TrimSpace(stdout.String()) != file {
+		t.Errorf("generated sitemap files should be printed, but got %v", stdout.String())
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, loc := range []string{"https://example.com/", "https://example.com/products/sneaker", "https://example.com/products/boot"} {
+		if !strings.Contains(string(content), "<loc>"+loc+"</loc>") {
+			t.Errorf("sitemap should include %v, but got %v", loc, string(content))
+		}
+	}
+}
@@ -1,13 +1,21 @@
 package seo
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/qor/admin"
+	"github.com/qor/qor"
 	"github.com/qor/responder"
+	"github.com/qor/validations"
 )
 
 type seoController struct {
@@ -32,7 +40,9 @@ func (sc seoController) InlineEdit(context *admin.Context) {
 	if err != nil {
 		settingContext.AddError(err)
 	}
-	context.DB.Where("name = ?", name).First(result)
+	if context.DB.Where("name = ?", name).First(result).RecordNotFound() {
+		result = sc.Collection.newSetting(name)
+	}
 
 	if seoSetting, ok := result.(QorSEOSettingInterface); ok {
 		seoSetting.SetCollection(sc.Collection)
@@ -41,10 +51,12 @@ func (sc seoController) InlineEdit(context *admin.Context) {
 	responder.With("html", func() {
 		settingContext.Execute("edit", struct {
 			Setting interface{}
+			Draft   QorSEOSettingDraftInterface
 			EditURL string
 			Metas   []*admin.Section
 		}{
-			Setting: result,
+			Setting: seoEditingSetting(result),
+			Draft:   seoDraft(result),
 			EditURL: sc.Collection.SEOSettingURL(name),
 			Metas:   seoSettingMetas(sc.Collection),
 		})
@@ -62,13 +74,17 @@ func (sc seoController) Update(context *admin.Context) {
 	if err != nil {
 		settingContext.AddError(err)
 	}
-	context.DB.Where("name = ?", name).First(result)
-	if context.DB.NewRecord(result) {
-		context.Request.Form["QorResource.Name"] = []string{name}
-		context.Request.Form["QorResource.Setting.Type"] = []string{name}
+	// settings not created by Collection.Sync are created when saved
+	if context.DB.Where("name = ?", name).First(result).RecordNotFound() {
+		result = sc.Collection.newSetting(name)
+		if name != sc.Collection.Name {
+			context.Request.Form["QorResource.Name"] = []string{name}
+			context.Request.Form["QorResource.Setting.Type"] = []string{name}
+		}
 	}
 
 	seoSettingInterface := result.(QorSEOSettingInterface)
+	liveSetting := seoSettingInterface.GetSEOSetting()
 	if seoSettingInterface.GetIsGlobalSEO() {
 		globalSetting := make(map[string]string)
 		for fieldWithPrefix := range context.Request.Form {
@@ -78,12 +94,51 @@ func (sc seoController) Update(context *admin.Context) {
 			}
 		}
 		seoSettingInterface.SetGlobalSetting(globalSetting)
+
+		for _, provider := range VerificationProviders {
+			if err := provider.validate(globalSetting[provider.Field]); err != nil {
+				settingContext.AddError(validations.NewError(result, provider.Field, err.Error()))
+			}
+		}
 	}
 
 	res := settingContext.Resource
+	action := context.Request.Form.Get("QorSEOAction")
+	draftInterface, supportDraft := result.(QorSEOSettingDraftInterface)
+	if (action == "draft" || action == "discard") && !supportDraft {
+		settingContext.AddError(errors.New("seo: setting model doesn't support drafts"))
+	}
+
 	if !settingContext.HasError() {
-		if settingContext.AddError(res.Decode(settingContext.Context, result)); !settingContext.HasError() {
+		switch action {
+		case "discard":
+			draftInterface.DiscardDraft()
 			settingContext.AddError(res.CallSave(result, settingContext.Context))
+		case "draft":
+			publishAt, err := parsePublishAt(context.Request.Form.Get("QorSEOPublishAt"))
+			if settingContext.AddError(err); !settingContext.HasError() {
+				if settingContext.AddError(res.Decode(settingContext.Context, result)); !settingContext.HasError() {
+					draftInterface.SetDraft(seoSettingInterface.GetSEOSetting(), publishAt)
+					if settingContext.AddError(setSEOSetting(result, liveSetting)); !settingContext.HasError() {
+						settingContext.AddError(res.CallSave(result, settingContext.Context))
+					}
+				}
+			}
+		default:
+			if settingContext.AddError(res.Decode(settingContext.Context, result)); !settingContext.HasError() {
+				if supportDraft {
+					draftInterface.DiscardDraft()
+				}
+				// the setting and its new version are saved together
+				settingContext.AddError(settingContext.GetDB().Transaction(func(tx *gorm.DB) error {
+					txContext := settingContext.Context.Clone()
+					txContext.DB = tx
+					if err := res.CallSave(result, txContext); err != nil {
+						return err
+					}
+					return sc.Collection.saveVersion(txContext, seoSettingInterface, 0)
+				}))
+			}
 		}
 	}
 
@@ -98,3 +153,218 @@ func (sc seoController) Update(context *admin.Context) {
 		}
 	}).Respond(context.Request)
 }
+
+func (sc seoController) Versions(context *admin.Context) {
+	settingContext := context.NewResourceContext(sc.Collection.SettingResource)
+	name := context.Request.Form.Get("name")
+	versions, err := sc.Collection.Versions(context.Context, name)
+	settingContext.AddError(err)
+
+	type versionWithChanges struct {
+		QorSEOSettingVersion
+		Changes []SettingChange
+	}
+
+	var results []versionWithChanges
+	for idx, version := range versions {
+		var previous QorSEOSettingVersion
+		if idx+1 < len(versions) {
+			previous = versions[idx+1]
+		}
+		results = append(results, versionWithChanges{QorSEOSettingVersion: version, Changes: version.Diff(previous)})
+	}
+
+	responder.With("html", func() {
+		settingContext.Execute("versions", struct {
+			Name     string
+			URL      string
+			Versions []versionWithChanges
+		}{
+			Name:     name,
+			URL:      sc.Collection.SEOSettingVersionsURL(name),
+			Versions: results,
+		})
+	}).With("json", func() {
+		json.NewEncoder(context.Writer).Encode(results)
+	}).Respond(context.Request)
+}
+
+func (sc seoController) RestoreVersion(context *admin.Context) {
+	settingContext := context.NewResourceContext(sc.Collection.SettingResource)
+	name := context.Request.Form.Get("name")
+	id, err := strconv.ParseUint(context.Request.Form.Get("version"), 10, 64)
+	if settingContext.AddError(err); !settingContext.HasError() {
+		settingContext.AddError(sc.Collection.RestoreVersion(settingContext.Context, uint(id)))
+	}
+
+	responder.With("html", func() {
+		if settingContext.HasError() {
+			context.Flash(settingContext.Error(), "error")
+		} else {
+			context.Flash(string(context.Admin.T(context.Context, "qor_seo.versions.restored", "Version restored")), "success")
+		}
+		http.Redirect(context.Writer, context.Request, sc.Collection.SEOSettingVersionsURL(name), http.StatusFound)
+	}).With("json", func() {
+		if settingContext.HasError() {
+			context.Writer.WriteHeader(admin.HTTPUnprocessableEntity)
+			settingContext.JSON("edit", map[string]interface{}{"errors": settingContext.GetErrors()})
+		} else {
+			context.Writer.WriteHeader(http.StatusOK)
+		}
+	}).Respond(context.Request)
+}
+
+func (sc seoController) Orphaned(context *admin.Context) {
+	settingContext := context.NewResourceContext(sc.Collection.SettingResource)
+	name := context.Request.Form.Get("name")
+
+	switch context.Request.Form.Get("QorSEOAction") {
+	case "delete":
+		settingContext.AddError(sc.Collection.DeleteOrphanedSetting(context.DB, name))
+	case "rename":
+		settingContext.AddError(sc.Collection.RenameSetting(context.DB, name, context.Request.Form.Get("to")))
+	default:
+		settingContext.AddError(errors.New("seo: unknown action for orphaned settings"))
+	}
+
+	responder.With("html", func() {
+		if settingContext.HasError() {
+			context.Flash(settingContext.Error(), "error")
+		} else {
+			context.Flash(string(context.Admin.T(context.Context, "qor_seo.orphaned.updated", "Orphaned setting updated")), "success")
+		}
+		http.Redirect(context.Writer, context.Request, path.Join(sc.Collection.SettingResource.GetAdmin().GetRouter().Prefix, context.Resource.ToParam()), http.StatusFound)
+	}).With("json", func() {
+		if settingContext.HasError() {
+			context.Writer.WriteHeader(admin.HTTPUnprocessableEntity)
+			settingContext.JSON("edit", map[string]interface{}{"errors": settingContext.GetErrors()})
+		} else {
+			context.Writer.WriteHeader(http.StatusOK)
+		}
+	}).Respond(context.Request)
+}
+
+func (sc seoController) Export(context *admin.Context) {
+	format := context.Request.Form.Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	settings, err := sc.Collection.Export(context.Context)
+	if err != nil {
+		http.Error(context.Writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	context.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("seo-settings-%v.%v", time.Now().Format("20060102150405"), format)))
+	if err := WriteSettings(context.Writer, settings, format); err != nil {
+		http.Error(context.Writer, err.Error(), http.StatusBadRequest)
+	}
+}
+
+type seoImportPage struct {
+	Collection *Collection
+	Results    []ImportResult
+	Options    ImportOptions
+	Imported   bool
+}
+
+func (sc seoController) ImportPage(context *admin.Context) {
+	settingContext := context.NewResourceContext(sc.Collection.SettingResource)
+	settingContext.Execute("import", seoImportPage{Collection: sc.Collection})
+}
+
+func (sc seoController) Import(context *admin.Context) {
+	settingContext := context.NewResourceContext(sc.Collection.SettingResource)
+	options := ImportOptions{
+		DryRun:   context.Request.Form.Get("QorSEOImportDryRun") == "true",
+		Conflict: ImportConflict(context.Request.Form.Get("QorSEOImportConflict")),
+	}
+
+	var results []ImportResult
+	file, header, err := context.Request.FormFile("QorSEOImportFile")
+	if settingContext.AddError(err); !settingContext.HasError() {
+		defer file.Close()
+		settings, err := ReadSettings(file, strings.TrimPrefix(path.Ext(header.Filename), "."))
+		if settingContext.AddError(err); !settingContext.HasError() {
+			results, err = sc.Collection.Import(settingContext.Context, settings, options)
+			settingContext.AddError(err)
+		}
+	}
+
+	responder.With("html", func() {
+		settingContext.Execute("import", seoImportPage{Collection: sc.Collection, Results: results, Options: options, Imported: true})
+	}).With("json", func() {
+		var errs []string
+		for _, err := range settingContext.GetErrors() {
+			errs = append(errs, err.Error())
+		}
+
+		context.Writer.Header().Set("Content-Type", "application/json")
+		if settingContext.HasError() {
+			context.Writer.WriteHeader(admin.HTTPUnprocessableEntity)
+		}
+		json.NewEncoder(context.Writer).Encode(map[string]interface{}{"Results": results, "Errors": errs})
+	}).Respond(context.Request)
+}
+
+// Audit show the last audit report, audits are run in background by RunAudit as scanning all records is slow
+func (sc seoController) Audit(context *admin.Context) {
+	settingContext := context.NewResourceContext(sc.Collection.SettingResource)
+	report, running, err := sc.Collection.auditRunner.last()
+	settingContext.AddError(err)
+
+	settingContext.Execute("audit", struct {
+		Collection *Collection
+		Report     *AuditReport
+		Running    bool
+	}{
+		Collection: sc.Collection,
+		Report:     report,
+		Running:    running,
+	})
+}
+
+// RunAudit start an audit in background
+func (sc seoController) RunAudit(context *admin.Context) {
+	sc.Collection.auditRunner.start(sc.Collection, &qor.Context{DB: context.GetDB()})
+
+	responder.With("html", func() {
+		http.Redirect(context.Writer, context.Request, sc.Collection.AuditURL(), http.StatusFound)
+	}).With("json", func() {
+		context.Writer.WriteHeader(http.StatusAccepted)
+	}).Respond(context.Request)
+}
+
+// AuditExport export the last audit report as CSV
+func (sc seoController) AuditExport(context *admin.Context) {
+	report, _, err := sc.Collection.auditRunner.last()
+	if err != nil {
+		http.Error(context.Writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if report == nil {
+		http.Error(context.Writer, "seo: no audit report, run an audit first", http.StatusNotFound)
+		return
+	}
+
+	context.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	context.Writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("seo-audit-%v.csv", report.CreatedAt.Format("20060102150405"))))
+	report.WriteCSV(context.Writer)
+}
+
+// parsePublishAt parse scheduled publish time from datetime-local inputs or RFC3339
+func parsePublishAt(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
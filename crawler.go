@@ -0,0 +1,389 @@
+package seo
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CrawlIssueType kind of problem found by crawler
+type CrawlIssueType string
+
+// Crawl issue types
+const (
+	CrawlMissingCanonical       CrawlIssueType = "missing_canonical"
+	CrawlMultipleTitles         CrawlIssueType = "multiple_titles"
+	CrawlBrokenLink             CrawlIssueType = "broken_link"
+	CrawlRelativeOpenGraphURL   CrawlIssueType = "relative_og_url"
+	CrawlRelativeOpenGraphImage CrawlIssueType = "relative_og_image"
+	CrawlNoindexInSitemap       CrawlIssueType = "noindex_in_sitemap"
+	CrawlBrokenSitemap          CrawlIssueType = "broken_sitemap"
+)
+
+// Crawler crawl pages served by a http.Handler in process, without network, and check their rendered head tags
+//
+//	report, err := seo.Crawler{Handler: mux, Seeds: []string{"/"}, Sitemaps: []string{"/sitemap.xml"}}.Crawl()
+type Crawler struct {
+	Handler http.Handler
+	// Host used to build requests, links to other hosts are not followed, default is "localhost"
+	Host string
+	// Seeds paths where crawling starts from
+	Seeds []string
+	// Sitemaps paths of sitemap or sitemap index files, urls listed in them will be crawled too
+	Sitemaps []string
+	// MaxPages max pages to crawl, default is 1000
+	MaxPages int
+}
+
+// CrawlIssue a problem found on a crawled page
+type CrawlIssue struct {
+	Type  CrawlIssueType
+	URL   string
+	Value string
+}
+
+// CrawledPage head tags of a crawled page
+type CrawledPage struct {
+	URL             string
+	StatusCode      int
+	ContentType     string
+	Titles          []string
+	Canonical       string
+	Robots          string
+	OpenGraphURL    string
+	OpenGraphImages []string
+	Links           []string
+}
+
+// Noindex check if the page shouldn't be indexed by robots
+func (page CrawledPage) Noindex() bool {
+	return strings.Contains(strings.ToLower(page.Robots), "noindex")
+}
+
+// IsHTML check if the page is a HTML document
+func (page CrawledPage) IsHTML() bool {
+	return strings.Contains(page.ContentType, "text/html")
+}
+
+// CrawlReport result of a crawl
+type CrawlReport struct {
+	Pages  []*CrawledPage
+	Issues []CrawlIssue
+}
+
+// IssuesByType return issues of given type
+func (report CrawlReport) IssuesByType(typ CrawlIssueType) (issues []CrawlIssue) {
+	for _, issue := range report.Issues {
+		if issue.Type == typ {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Crawl follow internal links from seeds and sitemaps, and report problems of rendered pages
+func (crawler Crawler) Crawl() (*CrawlReport, error) {
+	if crawler.Handler == nil {
+		return nil, errors.New("seo: crawler requires a http.Handler")
+	}
+
+	if crawler.Host == "" {
+		crawler.Host = "localhost"
+	}
+
+	if crawler.MaxPages == 0 {
+		crawler.MaxPages = 1000
+	}
+
+	var (
+		report    = &CrawlReport{}
+		visited   = map[string]*CrawledPage{}
+		referrers = map[string][]string{}
+		inSitemap = map[string]bool{}
+		queue     []string
+	)
+
+	enqueue := func(link, referrer string) {
+		if link, ok := crawler.internalPath(link, referrer); ok {
+			if referrer != "" {
+				referrers[link] = append(referrers[link], referrer)
+			}
+			if _, ok := visited[link]; !ok {
+				visited[link] = nil
+				queue = append(queue, link)
+			}
+		}
+	}
+
+	for _, seed := range crawler.Seeds {
+		enqueue(seed, "")
+	}
+
+	addIssue := func(typ CrawlIssueType, link, value string) {
+		report.Issues = append(report.Issues, CrawlIssue{Type: typ, URL: link, Value: value})
+	}
+
+	for _, sitemap := range crawler.Sitemaps {
+		for _, loc := range crawler.sitemapLocations(sitemap, 0, addIssue) {
+			if link, ok := crawler.internalPath(loc, ""); ok {
+				inSitemap[link] = true
+				enqueue(link, "")
+			}
+		}
+	}
+
+	for len(queue) > 0 && len(report.Pages) < crawler.MaxPages {
+		link := queue[0]
+		queue = queue[1:]
+
+		page := crawler.fetch(link)
+		visited[link] = page
+		report.Pages = append(report.Pages, page)
+
+		for _, target := range page.Links {
+			enqueue(target, link)
+		}
+	}
+
+	for _, page := range report.Pages {
+		if page.StatusCode >= http.StatusBadRequest {
+			for _, referrer := range referrers[page.URL] {
+				addIssue(CrawlBrokenLink, referrer, page.URL)
+			}
+			if len(referrers[page.URL]) == 0 {
+				addIssue(CrawlBrokenLink, page.URL, page.URL)
+			}
+			continue
+		}
+
+		if page.StatusCode != http.StatusOK {
+			continue
+		}
+
+		if inSitemap[page.URL] && page.Noindex() {
+			addIssue(CrawlNoindexInSitemap, page.URL, page.Robots)
+		}
+
+		if !page.IsHTML() {
+			continue
+		}
+
+		if page.Canonical == "" {
+			addIssue(CrawlMissingCanonical, page.URL, "")
+		}
+
+		if len(page.Titles) > 1 {
+			addIssue(CrawlMultipleTitles, page.URL, strings.Join(page.Titles, " | "))
+		}
+
+		if page.OpenGraphURL != "" && !isAbsoluteURL(page.OpenGraphURL) {
+			addIssue(CrawlRelativeOpenGraphURL, page.URL, page.OpenGraphURL)
+		}
+
+		for _, image := range page.OpenGraphImages {
+			if !isAbsoluteURL(image) {
+				addIssue(CrawlRelativeOpenGraphImage, page.URL, image)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		if report.Issues[i].Type != report.Issues[j].Type {
+			return report.Issues[i].Type < report.Issues[j].Type
+		}
+		return report.Issues[i].URL < report.Issues[j].URL
+	})
+
+	return report, nil
+}
+
+// internalPath resolve link against referrer, return its path and query if it points to crawler's host
+func (crawler Crawler) internalPath(link, referrer string) (string, bool) {
+	link = strings.TrimSpace(strings.SplitN(link, "#", 2)[0])
+	if link == "" {
+		return "", false
+	}
+
+	base := &url.URL{Scheme: "http", Host: crawler.Host, Path: "/"}
+	if referrer != "" {
+		if u, err := base.Parse(referrer); err == nil {
+			base = u
+		}
+	}
+
+	u, err := base.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Host, crawler.Host) {
+		return "", false
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.RequestURI(), true
+}
+
+func (crawler Crawler) serve(link string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "http://"+crawler.Host+link, nil)
+	req.Header.Set("User-Agent", "QorSEOCrawler")
+	recorder := httptest.NewRecorder()
+	crawler.Handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (crawler Crawler) fetch(link string) *CrawledPage {
+	recorder := crawler.serve(link)
+	page := &CrawledPage{
+		URL:         link,
+		StatusCode:  recorder.Code,
+		ContentType: recorder.Header().Get("Content-Type"),
+		Robots:      recorder.Header().Get("X-Robots-Tag"),
+	}
+
+	if location := recorder.Header().Get("Location"); location != "" && recorder.Code >= 300 && recorder.Code < 400 {
+		page.Links = append(page.Links, location)
+		return page
+	}
+
+	if recorder.Code != http.StatusOK || !page.IsHTML() {
+		return page
+	}
+
+	doc, err := html.Parse(recorder.Body)
+	if err != nil {
+		return page
+	}
+
+	var walk func(node *html.Node, inHead bool)
+	walk = func(node *html.Node, inHead bool) {
+		if node.Type == html.ElementNode {
+			switch node.Data {
+			case "head":
+				inHead = true
+			case "title":
+				if inHead {
+					page.Titles = append(page.Titles, textOf(node))
+				}
+			case "link":
+				if inHead && hasToken(attrOf(node, "rel"), "canonical") {
+					page.Canonical = attrOf(node, "href")
+				}
+			case "meta":
+				if inHead {
+					name := attrOf(node, "name")
+					if property := attrOf(node, "property"); property != "" {
+						name = property
+					}
+
+					switch strings.ToLower(name) {
+					case "robots":
+						page.Robots = strings.TrimPrefix(page.Robots+", "+attrOf(node, "content"), ", ")
+					case "og:url":
+						page.OpenGraphURL = attrOf(node, "content")
+					case "og:image":
+						page.OpenGraphImages = append(page.OpenGraphImages, attrOf(node, "content"))
+					}
+				}
+			case "a":
+				if href := attrOf(node, "href"); href != "" && !strings.HasPrefix(href, "#") {
+					page.Links = append(page.Links, href)
+				}
+			}
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, inHead)
+		}
+	}
+	walk(doc, false)
+
+	return page
+}
+
+type sitemapXML struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// sitemapLocations return urls listed in a sitemap, sitemap indexes are followed.
+// Sitemaps that are not found or couldn't be parsed are reported as broken sitemaps
+func (crawler Crawler) sitemapLocations(link string, depth int, addIssue func(typ CrawlIssueType, link, value string)) (locations []string) {
+	if depth > 3 {
+		return nil
+	}
+
+	path, ok := crawler.internalPath(link, "")
+	if !ok {
+		addIssue(CrawlBrokenSitemap, link, "sitemap is not on the crawled host")
+		return nil
+	}
+
+	recorder := crawler.serve(path)
+	if recorder.Code != http.StatusOK {
+		addIssue(CrawlBrokenSitemap, path, fmt.Sprintf("responded with status %v", recorder.Code))
+		return nil
+	}
+
+	var sitemap sitemapXML
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &sitemap); err != nil {
+		addIssue(CrawlBrokenSitemap, path, err.Error())
+		return nil
+	}
+
+	if sitemap.XMLName.Local == "sitemapindex" {
+		for _, s := range sitemap.Sitemaps {
+			locations = append(locations, crawler.sitemapLocations(strings.TrimSpace(s.Loc), depth+1, addIssue)...)
+		}
+		return locations
+	}
+
+	for _, u := range sitemap.URLs {
+		locations = append(locations, strings.TrimSpace(u.Loc))
+	}
+	return locations
+}
+
+func attrOf(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return strings.TrimSpace(attr.Val)
+		}
+	}
+	return ""
+}
+
+// hasToken check if a space-separated token list like rel="canonical alternate" contains the token
+func hasToken(tokens, token string) bool {
+	for _, t := range strings.Fields(tokens) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func textOf(node *html.Node) string {
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode {
+			text.WriteString(child.Data)
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+func isAbsoluteURL(str string) bool {
+	u, err := url.Parse(str)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
@@ -0,0 +1,106 @@
+package seo
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCrawler(t *testing.T) {
+	pages := map[string]string{
+		"/": `<html><head><title>Home</title><link rel="canonical" href="http://localhost/">
+<meta property="og:url" content="http://localhost/"><meta property="og:image" content="/logo.png"></head>
+<body><a href="/products">Products</a><a href="/missing">Missing</a><a href="http://example.com/">External</a><a href="#top">Top</a></body></html>`,
+		"/products": `<html><head><title>Products</title><title>Again</title><meta property="og:url" content="/products"></head>
+<body><a href="products/1">Product</a><a href="/old">Old</a></body></html>`,
+		"/products/1": `<html><head><title>Product</title><link rel="canonical" href="http://localhost/products/1"><meta name="robots" content="noindex, follow"></head><body><a href="/">Home</a></body></html>`,
+		"/hidden":     `<html><head><title>Hidden</title><link rel="canonical" href="http://localhost/hidden"></head><body></body></html>`,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/old" {
+			http.Redirect(w, req, "/products/1", http.StatusMovedPermanently)
+			return
+		}
+		if content, ok := pages[req.URL.Path]; ok {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, content)
+			return
+		}
+		http.NotFound(w, req)
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><sitemapindex><sitemap><loc>http://localhost/sitemap-pages.xml</loc></sitemap></sitemapindex>`)
+	})
+	mux.HandleFunc("/sitemap-pages.xml", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><urlset><url><loc>http://localhost/products/1</loc></url><url><loc>http://localhost/hidden</loc></url></urlset>`)
+	})
+
+	report, err := Crawler{Handler: mux, Seeds: []string{"/"}, Sitemaps: []string{"/sitemap.xml"}}.Crawl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Pages) != 6 {
+		t.Errorf("should crawl 6 pages, but got %v", len(report.Pages))
+	}
+
+	expects := map[CrawlIssueType][]CrawlIssue{
+		CrawlMissingCanonical:       {{Type: CrawlMissingCanonical, URL: "/products"}},
+		CrawlMultipleTitles:         {{Type: CrawlMultipleTitles, URL: "/products", Value: "Products | Again"}},
+		CrawlBrokenLink:             {{Type: CrawlBrokenLink, URL: "/", Value: "/missing"}},
+		CrawlRelativeOpenGraphURL:   {{Type: CrawlRelativeOpenGraphURL, URL: "/products", Value: "/products"}},
+		CrawlRelativeOpenGraphImage: {{Type: CrawlRelativeOpenGraphImage, URL: "/", Value: "/logo.png"}},
+		CrawlNoindexInSitemap:       {{Type: CrawlNoindexInSitemap, URL: "/products/1", Value: "noindex, follow"}},
+	}
+
+	for typ, expect := range expects {
+		issues := report.IssuesByType(typ)
+		if fmt.Sprint(issues) != fmt.Sprint(expect) {
+			t.Errorf("%v: expect %v, but got %v", typ, expect, issues)
+		}
+	}
+
+	if len(report.Issues) != len(expects) {
+		t.Errorf("should only get expected issues, but got %v", report.Issues)
+	}
+}
+
+func TestCrawlerCanonicalRelTokens(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>Home</title><link rel="alternate  CANONICAL" href="http://localhost/"></head><body></body></html>`)
+	})
+
+	report, err := Crawler{Handler: handler, Seeds: []string{"/"}}.Crawl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Pages) != 1 || report.Pages[0].Canonical != "http://localhost/" || len(report.IssuesByType(CrawlMissingCanonical)) != 0 {
+		t.Errorf("canonical should be found in rel token list, but got %#v", report)
+	}
+}
+
+func TestCrawlerBrokenSitemaps(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>Home</title><link rel="canonical" href="http://localhost/"></head><body></body></html>`)
+	})
+	mux.HandleFunc("/missing-sitemap.xml", http.NotFound)
+	mux.HandleFunc("/invalid-sitemap.xml", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `<urlset><url><loc>http://localhost/</loc></url>`)
+	})
+
+	report, err := Crawler{Handler: mux, Sitemaps: []string{"/missing-sitemap.xml", "/invalid-sitemap.xml", "http://example.com/sitemap.xml"}}.Crawl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := report.IssuesByType(CrawlBrokenSitemap)
+	if len(issues) != 3 || issues[0].URL != "/invalid-sitemap.xml" || issues[1].URL != "/missing-sitemap.xml" || issues[1].Value != "responded with status 404" || issues[2].URL != "http://example.com/sitemap.xml" {
+		t.Errorf("broken sitemaps should be reported, but got %v", issues)
+	}
+}
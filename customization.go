@@ -0,0 +1,115 @@
+package seo
+
+// CustomizableFields fields of Setting that could be customized by records one by one, OpenGraphImage covers both image url and image from media library
+var CustomizableFields = []string{
+	"Title", "Description", "Keywords",
+	"OpenGraphTitle", "OpenGraphDescription", "OpenGraphURL", "OpenGraphType", "OpenGraphImage", "OpenGraphMetadata", "OpenGraphObject",
+}
+
+// IsCustomized check if a field of the setting overrides the value of page setting.
+// Settings saved before CustomizedFields customize all fields when EnabledCustomize is true
+func (setting Setting) IsCustomized(field string) bool {
+	if len(setting.CustomizedFields) == 0 {
+		return setting.EnabledCustomize
+	}
+
+	for _, customized := range setting.CustomizedFields {
+		if customized == field {
+			return true
+		}
+	}
+	return false
+}
+
+// inherit return the parent setting with customized fields of the setting
+func (setting Setting) inherit(parent Setting) Setting {
+	result := parent
+	for _, field := range CustomizableFields {
+		if setting.IsCustomized(field) {
+			copySettingField(&result, setting, field)
+		}
+	}
+	result.EnabledCustomize = setting.EnabledCustomize
+	result.CustomizedFields = setting.CustomizedFields
+	return result
+}
+
+// setCustomizedFields set customized fields, EnabledCustomize is true if any field is customized
+func (setting *Setting) setCustomizedFields(fields []string) {
+	setting.CustomizedFields = []string{}
+	for _, field := range fields {
+		for _, customizable := range CustomizableFields {
+			if field == customizable {
+				setting.CustomizedFields = append(setting.CustomizedFields, field)
+				break
+			}
+		}
+	}
+	setting.EnabledCustomize = len(setting.CustomizedFields) > 0
+}
+
+// isEmptyCustomization check if the setting is customized but all customized fields are empty
+func (setting Setting) isEmptyCustomization() bool {
+	if !setting.EnabledCustomize {
+		return false
+	}
+
+	for _, field := range CustomizableFields {
+		if setting.IsCustomized(field) && !isSettingFieldEmpty(setting, field) {
+			return false
+		}
+	}
+	return true
+}
+
+func copySettingField(dst *Setting, src Setting, field string) {
+	switch field {
+	case "Title":
+		dst.Title = src.Title
+	case "Description":
+		dst.Description = src.Description
+	case "Keywords":
+		dst.Keywords = src.Keywords
+	case "OpenGraphTitle":
+		dst.OpenGraphTitle = src.OpenGraphTitle
+	case "OpenGraphDescription":
+		dst.OpenGraphDescription = src.OpenGraphDescription
+	case "OpenGraphURL":
+		dst.OpenGraphURL = src.OpenGraphURL
+	case "OpenGraphType":
+		dst.OpenGraphType = src.OpenGraphType
+	case "OpenGraphImage":
+		dst.OpenGraphImageURL = src.OpenGraphImageURL
+		dst.OpenGraphImageFromMediaLibrary = src.OpenGraphImageFromMediaLibrary
+	case "OpenGraphMetadata":
+		dst.OpenGraphMetadata = src.OpenGraphMetadata
+	case "OpenGraphObject":
+		dst.OpenGraphObject = src.OpenGraphObject
+	}
+}
+
+func isSettingFieldEmpty(setting Setting, field string) bool {
+	switch field {
+	case "Title":
+		return setting.Title == ""
+	case "Description":
+		return setting.Description == ""
+	case "Keywords":
+		return setting.Keywords == ""
+	case "OpenGraphTitle":
+		return setting.OpenGraphTitle == ""
+	case "OpenGraphDescription":
+		return setting.OpenGraphDescription == ""
+	case "OpenGraphURL":
+		return setting.OpenGraphURL == ""
+	case "OpenGraphType":
+		return setting.OpenGraphType == ""
+	case "OpenGraphImage":
+		return setting.OpenGraphImageURL == "" && len(setting.OpenGraphImageFromMediaLibrary.Files) == 0
+	case "OpenGraphMetadata":
+		return len(setting.OpenGraphMetadata) == 0
+	case "OpenGraphObject":
+		return setting.OpenGraphObject == OpenGraphObject{}
+	}
+	return true
+}
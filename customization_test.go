@@ -0,0 +1,78 @@
+package seo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/qor"
+)
+
+type CustomizedProduct struct {
+	gorm.Model
+	Name string
+	SEO  Setting `seo:"type:CategoryPage"`
+}
+
+func TestCustomizeFields(t *testing.T) {
+	setupSeoCollection()
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} {{Name}}", Description: "Buy {{Name}}", OpenGraphImageURL: "/default.jpg"})
+	context := &qor.Context{DB: db}
+
+	product := CustomizedProduct{SEO: Setting{Title: "Custom {{Name}}", Description: "Not used", EnabledCustomize: true, CustomizedFields: []string{"Title"}}}
+	setting := collection.GetSEOSetting(context, "CategoryPage", "Shoes", product)
+	if setting.Title != "Custom Shoes" || setting.Description != "Buy Shoes" || setting.OpenGraphImageURL != "/default.jpg" {
+		t.Errorf("only customized fields should override page setting, but got %#v", setting)
+	}
+
+	product.SEO.CustomizedFields = []string{"OpenGraphImage"}
+	if setting = collection.GetSEOSetting(context, "CategoryPage", "Shoes", product); setting.Title != "Qor Shoes" || setting.OpenGraphImageURL != "" {
+		t.Errorf("customized open graph image should override page setting, but got %#v", setting)
+	}
+
+	product.SEO.CustomizedFields = nil
+	if setting = collection.GetSEOSetting(context, "CategoryPage", "Shoes", product); setting.Title != "Custom Shoes" || setting.Description != "Not used" || setting.OpenGraphImageURL != "" {
+		t.Errorf("settings without customized fields should customize all fields, but got %#v", setting)
+	}
+
+	if !(Setting{EnabledCustomize: true, CustomizedFields: []string{"Title"}}).isEmptyCustomization() ||
+		(Setting{EnabledCustomize: true, CustomizedFields: []string{"Title"}, Description: "D"}).isEmptyCustomization() == false {
+		t.Errorf("empty customization should only check customized fields")
+	}
+}
+
+func TestCustomizeFieldsFromAdmin(t *testing.T) {
+	setupSeoCollection()
+	db.DropTableIfExists(&CustomizedProduct{})
+	db.AutoMigrate(&CustomizedProduct{})
+	res := Admin.AddResource(&CustomizedProduct{})
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	product := CustomizedProduct{Name: "Shoes", SEO: Setting{EnabledCustomize: true}}
+	db.Create(&product)
+
+	update := func(fields ...string) CustomizedProduct {
+		form := url.Values{"_method": {"PUT"}, "QorResource.Name": {"Shoes"}, "QorResource.SEO.Title": {"Custom"}, "QorResource.SEO.CustomizedFields": append([]string{""}, fields...)}
+		if _, err := http.PostForm(fmt.Sprintf("%v/admin/%v/%v", server.URL, res.ToParam(), product.ID), form); err != nil {
+			t.Fatal(err)
+		}
+
+		var result CustomizedProduct
+		db.First(&result, product.ID)
+		return result
+	}
+
+	if result := update("Title", "Unknown"); !result.SEO.EnabledCustomize || !reflect.DeepEqual(result.SEO.CustomizedFields, []string{"Title"}) || result.SEO.Title != "Custom" {
+		t.Errorf("customized fields should be saved, but got %#v", result.SEO)
+	}
+
+	if result := update(); result.SEO.EnabledCustomize || len(result.SEO.CustomizedFields) != 0 {
+		t.Errorf("setting should not be customized without customized fields, but got %#v", result.SEO)
+	}
+}
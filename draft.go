@@ -0,0 +1,70 @@
+package seo
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/qor/qor"
+)
+
+// draftInEffect return draft of a seo setting if it should be rendered for current request,
+// that's when its scheduled publish time has come, or the request is previewing it with the draft's preview token
+func draftInEffect(context *qor.Context, setting interface{}) (Setting, bool) {
+	draftInterface, ok := setting.(QorSEOSettingDraftInterface)
+	if !ok {
+		return Setting{}, false
+	}
+
+	draft, hasDraft := draftInterface.GetDraft()
+	if !hasDraft {
+		return Setting{}, false
+	}
+
+	if publishAt := draftInterface.GetPublishAt(); publishAt != nil && !publishAt.After(time.Now()) {
+		return draft, true
+	}
+
+	if token := draftInterface.GetPreviewToken(); token != "" && context != nil && context.Request != nil && context.Request.URL != nil {
+		if context.Request.URL.Query().Get(PreviewTokenParam) == token {
+			return draft, true
+		}
+	}
+
+	return Setting{}, false
+}
+
+// PublishScheduledDrafts publish drafts whose scheduled time has come and save them as new versions, return names of published settings.
+// Scheduled drafts are rendered from their publish time even without this, run it periodically to keep stored settings and history up to date
+func (collection *Collection) PublishScheduledDrafts(context *qor.Context) (names []string, err error) {
+	db := context.GetDB()
+	settings := collection.SettingResource.NewSlice()
+	if err = db.Where("has_draft = ? AND publish_at <= ?", true, time.Now()).Find(settings).Error; err != nil {
+		return nil, err
+	}
+
+	values := reflect.Indirect(reflect.ValueOf(settings))
+	for i := 0; i < values.Len(); i++ {
+		value := values.Index(i)
+		if value.Kind() != reflect.Ptr {
+			value = value.Addr()
+		}
+
+		setting, ok := value.Interface().(QorSEOSettingDraftInterface)
+		if !ok {
+			continue
+		}
+
+		setting.PublishDraft()
+		if err = db.Save(setting).Error; err != nil {
+			return names, err
+		}
+
+		seoSetting := setting.(QorSEOSettingInterface)
+		if err = collection.saveVersion(context, seoSetting, 0); err != nil {
+			return names, err
+		}
+		names = append(names, seoSetting.GetName())
+	}
+
+	return names, nil
+}
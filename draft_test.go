@@ -0,0 +1,94 @@
+package seo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qor/qor"
+)
+
+func TestDraftSEOSetting(t *testing.T) {
+	setupSeoCollection()
+	db.Delete(&QorSEOSettingVersion{})
+	createCategoryPageSetting(Setting{Title: "Live Title"})
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	saveDraft := func(title string, publishAt string) {
+		form := url.Values{
+			"_method":                   {"PUT"},
+			"QorSEOAction":              {"draft"},
+			"QorSEOPublishAt":           {publishAt},
+			"QorResource.Setting.Title": {title},
+			"QorResource.Setting.Type":  {"CategoryPage"},
+		}
+		if _, err := http.PostForm(server.URL+collection.SEOSettingURL("CategoryPage"), form); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	render := func(query string) string {
+		context := &qor.Context{DB: db, Request: httptest.NewRequest("GET", "/categories/1"+query, nil)}
+		return string(collection.Render(context, "CategoryPage"))
+	}
+
+	saveDraft("Draft Title", "")
+
+	var setting QorSEOSetting
+	db.First(&setting, "name = ?", "CategoryPage")
+	if setting.Setting.Title != "Live Title" || !setting.HasDraft || setting.Draft.Title != "Draft Title" || setting.PreviewToken == "" {
+		t.Fatalf("draft should be saved without changing live setting, but got %#v", setting)
+	}
+
+	if html := render(""); !strings.Contains(html, "<title>Live Title</title>") {
+		t.Errorf("should render live setting, but got %v", html)
+	}
+
+	if html := render("?" + PreviewTokenParam + "=" + setting.PreviewToken); !strings.Contains(html, "<title>Draft Title</title>") {
+		t.Errorf("should render draft with preview token, but got %v", html)
+	}
+
+	if html := render("?" + PreviewTokenParam + "=wrong"); !strings.Contains(html, "<title>Live Title</title>") {
+		t.Errorf("should render live setting with wrong preview token, but got %v", html)
+	}
+
+	saveDraft("Scheduled Title", time.Now().Add(-time.Minute).Format("2006-01-02T15:04"))
+	if html := render(""); !strings.Contains(html, "<title>Scheduled Title</title>") {
+		t.Errorf("should render scheduled draft after its publish time, but got %v", html)
+	}
+
+	names, err := collection.PublishScheduledDrafts(&qor.Context{DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setting = QorSEOSetting{}
+	db.First(&setting, "name = ?", "CategoryPage")
+	if len(names) != 1 || setting.Setting.Title != "Scheduled Title" || setting.HasDraft {
+		t.Errorf("scheduled draft should be published, but got %v %#v", names, setting)
+	}
+
+	if versions, _ := collection.Versions(&qor.Context{DB: db}, "CategoryPage"); len(versions) != 1 || versions[0].Setting.Title != "Scheduled Title" {
+		t.Errorf("published draft should be saved as a version, but got %#v", versions)
+	}
+
+	saveDraft("Discarded Title", time.Now().Add(time.Hour).Format("2006-01-02T15:04"))
+	if html := render(""); !strings.Contains(html, "<title>Scheduled Title</title>") {
+		t.Errorf("should not render draft before its publish time, but got %v", html)
+	}
+
+	form := url.Values{"_method": {"PUT"}, "QorSEOAction": {"discard"}}
+	if _, err := http.PostForm(server.URL+collection.SEOSettingURL("CategoryPage"), form); err != nil {
+		t.Fatal(err)
+	}
+
+	setting = QorSEOSetting{}
+	db.First(&setting, "name = ?", "CategoryPage")
+	if setting.HasDraft || setting.Setting.Title != "Scheduled Title" {
+		t.Errorf("draft should be discarded, but got %#v", setting)
+	}
+}
@@ -0,0 +1,67 @@
+package seo
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/qor/qor"
+)
+
+// SettingNotFoundError returned when a seo has no saved setting and objects don't have a customized one
+type SettingNotFoundError struct {
+	Name string
+}
+
+func (err *SettingNotFoundError) Error() string {
+	return fmt.Sprintf("seo: setting of %v is not found", err.Name)
+}
+
+// SettingDecodeError returned when a stored setting couldn't be decoded
+type SettingDecodeError struct {
+	Err error
+}
+
+func (err *SettingDecodeError) Error() string {
+	return fmt.Sprintf("seo: failed to decode setting, got %v", err.Err)
+}
+
+func (err *SettingDecodeError) Unwrap() error {
+	return err.Err
+}
+
+// TemplateError returned when seo tags or structured data couldn't be rendered
+type TemplateError struct {
+	Name string
+	Err  error
+}
+
+func (err *TemplateError) Error() string {
+	return fmt.Sprintf("seo: failed to render %v, got %v", err.Name, err.Err)
+}
+
+func (err *TemplateError) Unwrap() error {
+	return err.Err
+}
+
+// handleError pass errors of GetSEOSetting and Render to ErrorHandler, or log them if no ErrorHandler.
+// Not found errors are ignored unless ReportNotFound is true, as pages without saved setting are rendered with global variables
+func (collection Collection) handleError(context *qor.Context, err error) {
+	if _, ok := err.(*SettingNotFoundError); ok && !collection.ReportNotFound {
+		return
+	}
+
+	if collection.ErrorHandler != nil {
+		collection.ErrorHandler(context, err)
+		return
+	}
+	logError(context, err)
+}
+
+// logError log err with url of current request
+func logError(context *qor.Context, err error) {
+	var requestURL string
+	if context != nil && context.Request != nil && context.Request.URL != nil {
+		requestURL = context.Request.URL.String()
+	}
+	log.Printf("Error: %v in %s", err, requestURL)
+}
@@ -0,0 +1,71 @@
+package seo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/qor/qor"
+)
+
+func TestRenderErrors(t *testing.T) {
+	setupSeoCollection()
+	createGlobalSetting("Qor")
+	context := &qor.Context{DB: db}
+
+	var handled []error
+	collection.ErrorHandler = func(context *qor.Context, err error) {
+		handled = append(handled, err)
+	}
+
+	result, err := collection.RenderE(context, "CategoryPage", "Shoes")
+	var notFoundErr *SettingNotFoundError
+	if !errors.As(err, &notFoundErr) || notFoundErr.Name != "CategoryPage" || !strings.Contains(string(result), "<title></title>") {
+		t.Errorf("should return not found error with rendered tags if setting is not saved, but got %v, %v", result, err)
+	}
+
+	if setting, err := collection.GetSEOSettingE(context, "CategoryPage", Category{SEO: Setting{Title: "Customized", EnabledCustomize: true}}); err != nil || setting.Title != "Customized" {
+		t.Errorf("should not return error for customized setting, but got %#v, %v", setting, err)
+	}
+
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} - {{Name}}"})
+	if result, err := collection.RenderE(context, "CategoryPage", "Shoes"); err != nil || !strings.Contains(string(result), "<title>Qor - Shoes</title>") {
+		t.Errorf("should render setting without errors, but got %v, %v", result, err)
+	}
+
+	db.Exec("UPDATE qor_seo_settings SET setting = ? WHERE name = ?", "{invalid", "CategoryPage")
+	var decodeErr *SettingDecodeError
+	if _, err := collection.GetSEOSettingE(context, "CategoryPage", "Shoes"); !errors.As(err, &decodeErr) {
+		t.Errorf("should return decode error for invalid setting, but got %v", err)
+	}
+
+	var settings []QorSEOSetting
+	if err := db.Find(&settings).Error; err != nil || len(settings) != 2 {
+		t.Errorf("invalid setting should not fail queries, but got %v, %v", settings, err)
+	}
+
+	handled = nil
+	if result := collection.Render(context, "CategoryPage", "Shoes"); result != "" || len(handled) != 1 || !errors.As(handled[0], &decodeErr) {
+		t.Errorf("errors of Render should be passed to ErrorHandler, but got %v, %v", result, handled)
+	}
+
+	if result := collection.GetSEOSetting(context, "DefaultPage"); result.Title != "" || len(handled) != 1 {
+		t.Errorf("not found errors should not be passed to ErrorHandler by default, but got %v", handled)
+	}
+
+	collection.ReportNotFound = true
+	if result := collection.GetSEOSetting(context, "DefaultPage"); result.Title != "" || len(handled) != 2 || !errors.As(handled[1], &notFoundErr) {
+		t.Errorf("not found errors of GetSEOSetting should be passed to ErrorHandler with ReportNotFound, but got %v", handled)
+	}
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	var templateErr *TemplateError
+	if _, err := renderTemplateE(MicroProductTemplate, 1); !errors.As(err, &templateErr) {
+		t.Errorf("should return template error, but got %v", err)
+	}
+
+	if result := renderTemplate(MicroProductTemplate, 1); result != "" {
+		t.Errorf("template errors should not be written into pages, but got %v", result)
+	}
+}
@@ -7,16 +7,14 @@ import (
 	"github.com/qor/admin"
 )
 
+// seoSections return settings of registered seos, missing settings are not saved until they are edited, use Collection.Sync to create them
 func seoSections(context *admin.Context, collection *Collection) []interface{} {
 	settings := []interface{}{}
 	for _, seo := range collection.registeredSEO {
 		s := collection.SettingResource.NewStruct()
 		db := context.GetDB()
-		db.Where("name = ?", seo.Name).First(s)
-		if db.NewRecord(s) {
-			s.(QorSEOSettingInterface).SetName(seo.Name)
-			s.(QorSEOSettingInterface).SetSEOType(seo.Name)
-			db.Save(s)
+		if db.Where("name = ?", seo.Name).First(s).RecordNotFound() {
+			s = collection.newSetting(seo.Name)
 		}
 		s.(QorSEOSettingInterface).SetCollection(collection)
 		settings = append(settings, s)
@@ -31,12 +29,8 @@ func seoSettingMetas(collection *Collection) []*admin.Section {
 func seoGlobalSetting(context *admin.Context, collection *Collection) interface{} {
 	s := collection.SettingResource.NewStruct()
 	db := context.GetDB()
-	db.Where("is_global_seo = ? AND name = ?", true, collection.Name).First(s)
-	if db.NewRecord(s) {
-		s.(QorSEOSettingInterface).SetName(collection.Name)
-		s.(QorSEOSettingInterface).SetSEOType(collection.Name)
-		s.(QorSEOSettingInterface).SetIsGlobalSEO(true)
-		db.Save(s)
+	if db.Where("is_global_seo = ? AND name = ?", true, collection.Name).First(s).RecordNotFound() {
+		return collection.newSetting(collection.Name)
 	}
 	return s
 }
@@ -44,10 +38,9 @@ func seoGlobalSetting(context *admin.Context, collection *Collection) interface{
 func seoGlobalSettingValue(collection *Collection, setting QorSEOSettingInterface) interface{} {
 	value := reflect.Indirect(reflect.ValueOf(collection.globalResource.NewStruct()))
 	settingValue := setting.GetGlobalSetting()
-	for i := 0; i < value.NumField(); i++ {
-		fieldName := value.Type().Field(i).Name
-		if settingValue[fieldName] != "" {
-			value.Field(i).Set(reflect.ValueOf(settingValue[fieldName]))
+	for _, field := range globalSettingStructFields(value.Type()) {
+		if settingValue[field.Name] != "" {
+			value.FieldByIndex(field.Index).Set(reflect.ValueOf(settingValue[field.Name]))
 		}
 	}
 	return value.Interface()
@@ -62,8 +55,8 @@ func seoTagsByType(seo *SEO) (tags []string) {
 		return []string{}
 	}
 	value := reflect.Indirect(reflect.ValueOf(seo.collection.globalSetting))
-	for i := 0; i < value.NumField(); i++ {
-		tags = append(tags, value.Type().Field(i).Name)
+	for _, field := range globalSettingStructFields(value.Type()) {
+		tags = append(tags, field.Name)
 	}
 	for _, s := range seo.Varibles {
 		tags = append(tags, s)
@@ -71,43 +64,70 @@ func seoTagsByType(seo *SEO) (tags []string) {
 	return tags
 }
 
+// seoAppendDefaultValue fill fields that are not customized by the record with values of page setting, which are rendered for them
 func seoAppendDefaultValue(context *admin.Context, seo *SEO, resourceSeoValue interface{}) interface{} {
-	db := context.GetDB()
-	globalInteface := seo.collection.SettingResource.NewStruct()
-	db.Where("name = ?", seo.Name).First(globalInteface)
-	globalSetting := globalInteface.(QorSEOSettingInterface)
+	return seoInheritedSetting(context, seo, nil, resourceSeoValue).Setting
+}
+
+// seoInheritedSetting fill fields that are not customized by the record with values of its parents or page setting, with levels they come from.
+// The record is passed to SEO.Parent as value like it is usually rendered in front end
+func seoInheritedSetting(context *admin.Context, seo *SEO, record interface{}, resourceSeoValue interface{}) inheritedSetting {
 	setting := resourceSeoValue.(Setting)
-	if !setting.EnabledCustomize && setting.Title == "" && setting.Description == "" && setting.Keywords == "" {
-		setting.Title = globalSetting.GetTitle()
-		setting.Description = globalSetting.GetDescription()
-		setting.Keywords = globalSetting.GetKeywords()
+	if value := reflect.ValueOf(record); value.Kind() == reflect.Ptr && !value.IsNil() {
+		record = value.Elem().Interface()
 	}
+	levels, _ := seo.collection.inheritanceChain(context.Context, nil, seo, record, setting)
 
-	if !setting.EnabledCustomize {
-		if setting.OpenGraphTitle == "" {
-			setting.OpenGraphTitle = globalSetting.GetOpenGraphTitle()
-		}
-		if setting.OpenGraphDescription == "" {
-			setting.OpenGraphDescription = globalSetting.GetOpenGraphDescription()
-		}
-		if setting.OpenGraphURL == "" {
-			setting.OpenGraphURL = globalSetting.GetOpenGraphURL()
-		}
-		if setting.OpenGraphType == "" {
-			setting.OpenGraphType = globalSetting.GetOpenGraphType()
-		}
-		if setting.OpenGraphImageURL == "" {
-			setting.OpenGraphImageURL = globalSetting.GetOpenGraphImageURL()
-		}
-		if setting.OpenGraphImageFromMediaLibrary.URL() == "" {
-			setting.OpenGraphImageFromMediaLibrary = globalSetting.GetOpenGraphImageFromMediaLibrary()
+	result := resolveChain(levels)
+	result.Setting.Type = setting.Type
+	return result
+}
+
+// seoIsPageSetting check if the record is a page setting, which doesn't inherit values
+func seoIsPageSetting(record interface{}) bool {
+	_, ok := record.(QorSEOSettingInterface)
+	return ok
+}
+
+// seoEditingSetting return a copy of the setting with its draft as values when it has a draft, so editors continue editing the draft
+func seoEditingSetting(setting interface{}) interface{} {
+	if draft, ok := draftOf(setting); ok {
+		value := reflect.New(reflect.Indirect(reflect.ValueOf(setting)).Type())
+		value.Elem().Set(reflect.Indirect(reflect.ValueOf(setting)))
+		if setSEOSetting(value.Interface(), draft) == nil {
+			return value.Interface()
 		}
-		if len(setting.OpenGraphMetadata) == 0 {
-			setting.OpenGraphMetadata = globalSetting.GetOpenGraphMetadata()
+	}
+	return setting
+}
+
+func seoDraft(setting interface{}) QorSEOSettingDraftInterface {
+	if draftInterface, ok := setting.(QorSEOSettingDraftInterface); ok {
+		if _, hasDraft := draftInterface.GetDraft(); hasDraft {
+			return draftInterface
 		}
 	}
+	return nil
+}
 
-	return setting
+func draftOf(setting interface{}) (Setting, bool) {
+	if draftInterface := seoDraft(setting); draftInterface != nil {
+		return draftInterface.GetDraft()
+	}
+	return Setting{}, false
+}
+
+// seoOrphanedSettings return names of orphaned settings, errors are shown as no orphaned settings
+func seoOrphanedSettings(context *admin.Context, collection *Collection) []string {
+	names, _ := collection.OrphanedSettings(context.GetDB())
+	return names
+}
+
+func seoNames(collection *Collection) (names []string) {
+	for _, seo := range collection.registeredSEO {
+		names = append(names, seo.Name)
+	}
+	return names
 }
 
 func seoURL(collection *Collection, name string) string {
@@ -124,6 +144,14 @@ func registerFuncMap(a *admin.Admin) {
 		"seo_tags_by_type":         seoTagsByType,
 		"seo_append_default_value": seoAppendDefaultValue,
 		"seo_url_for":              seoURL,
+		"seo_editing_setting":      seoEditingSetting,
+		"seo_draft":                seoDraft,
+		"seo_orphaned_settings":    seoOrphanedSettings,
+		"seo_names":                seoNames,
+		"seo_of_meta":              seoOfMeta,
+		"seo_inherited_setting":    seoInheritedSetting,
+		"seo_is_page_setting":      seoIsPageSetting,
+		"seo_customizable_fields":  func() []string { return CustomizableFields },
 	}
 
 	for key, value := range funcMaps {
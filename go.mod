@@ -3,18 +3,23 @@ module github.com/qor/seo
 go 1.25.0
 
 require (
+	github.com/disintegration/imaging v1.6.3-0.20201218193011-d40f48ce0f09
 	github.com/fatih/color v1.9.0
 	github.com/jinzhu/gorm v1.9.16
 	github.com/qor/admin v1.2.1-0.20251125093313-4a292fdc9c5d
 	github.com/qor/media v0.0.0-20260205073501-f7c597c53aab
+	github.com/qor/oss v0.0.0-20241126061828-4629f3a3524a
 	github.com/qor/qor v1.3.1-0.20260203034140-88b8e649a105
 	github.com/qor/responder v0.0.0-20171031032654-b6def473574f
+	github.com/qor/validations v0.0.0-20171228122639-f364bca61b46
+	golang.org/x/image v0.43.0
+	golang.org/x/net v0.55.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/asaskevich/govalidator v0.0.0-20200428143746-21a406dcc535 // indirect
 	github.com/aymerick/douceur v0.2.0 // indirect
-	github.com/disintegration/imaging v1.6.3-0.20201218193011-d40f48ce0f09 // indirect
 	github.com/go-sql-driver/mysql v1.5.0 // indirect
 	github.com/gorilla/context v1.1.1 // indirect
 	github.com/gorilla/css v1.0.1 // indirect
@@ -30,14 +35,11 @@ require (
 	github.com/microcosm-cc/bluemonday v1.0.27 // indirect
 	github.com/qor/assetfs v0.0.0-20170713023933-ff57fdc13a14 // indirect
 	github.com/qor/middlewares v0.0.0-20170822143614-781378b69454 // indirect
-	github.com/qor/oss v0.0.0-20241126061828-4629f3a3524a // indirect
 	github.com/qor/roles v0.0.0-20171127035124-d6375609fe3e // indirect
 	github.com/qor/serializable_meta v0.0.0-20180510060738-5fd8542db417 // indirect
 	github.com/qor/session v0.0.0-20170907035918-8206b0adab70 // indirect
-	github.com/qor/validations v0.0.0-20171228122639-f364bca61b46 // indirect
 	github.com/theplant/cldr v0.0.0-20190423050709-9f76f7ce4ee8 // indirect
-	golang.org/x/image v0.43.0 // indirect
-	golang.org/x/net v0.55.0 // indirect
 	golang.org/x/sys v0.45.0 // indirect
+	golang.org/x/text v0.38.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
@@ -0,0 +1,235 @@
+package seo
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/qor"
+)
+
+// SEORoute map request paths to a registered seo
+type SEORoute struct {
+	// Path pattern of requests, segments like {Code} match any path segment, e.g: /products/{Code}
+	Path string
+	// SEO name of a registered seo
+	SEO string
+	// Objects return objects that are passed to Render, e.g: find the product with path params. It is optional
+	Objects func(req *http.Request, params map[string]string) []interface{}
+}
+
+// HeadInjector an http middleware that injects seo tags of matched routes into <head> of html responses,
+// existing <title>, description, keywords and meta tags rendered by seo for the page, e.g: open graph and twitter tags, are replaced. Other responses are not buffered
+type HeadInjector struct {
+	Collection *Collection
+	DB         *gorm.DB
+	Routes     []SEORoute
+}
+
+// maxHeadSize responses are written without injecting if </head> is not found in the first maxHeadSize bytes
+const maxHeadSize = 64 * 1024
+
+var (
+	headEndRegexp   = regexp.MustCompile(`(?i)</head\s*>`)
+	headStartRegexp = regexp.MustCompile(`(?i)<head[\s>]`)
+	headTitleRegexp = regexp.MustCompile(`(?is)<title[^>]*>.*?</title\s*>\s*`)
+	headMetaRegexp  = regexp.MustCompile(`(?i)<meta\s[^>]*?(?:name|property)\s*=\s*["']?([^"'\s>]+)[^>]*>\s*`)
+)
+
+// headOwnedMetas meta tags always rendered by Render, they are removed from responses even if they are rendered empty
+var headOwnedMetas = []string{"description", "keywords"}
+
+// isInjectedMeta check if the meta tag is rendered by Render, either it is always rendered, or it or the property it describes is rendered for the page,
+// e.g: og:image:width is removed if og:image is rendered, so structured properties don't describe another image
+func isInjectedMeta(name string, rendered map[string]bool) bool {
+	name = strings.ToLower(name)
+	for property := name; ; {
+		if rendered[property] {
+			return true
+		}
+
+		idx := strings.LastIndex(property, ":")
+		if idx < 0 {
+			break
+		}
+		property = property[:idx]
+	}
+
+	for _, owned := range headOwnedMetas {
+		if name == owned {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware inject seo tags into html responses of requests that match routes
+func (injector *HeadInjector) Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		route, params, ok := injector.match(req.URL.Path)
+		if !ok {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		writer := &headInjectingWriter{ResponseWriter: w, status: http.StatusOK, render: func() template.HTML {
+			var objects []interface{}
+			if route.Objects != nil {
+				objects = route.Objects(req, params)
+			}
+			return injector.Collection.Render(&qor.Context{DB: injector.DB, Request: req}, route.SEO, objects...)
+		}}
+		handler.ServeHTTP(writer, req)
+		writer.finish()
+	})
+}
+
+// match find the first route matches path, return the route and its path params
+func (injector *HeadInjector) match(path string) (SEORoute, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, route := range injector.Routes {
+		patterns := strings.Split(strings.Trim(route.Path, "/"), "/")
+		if len(patterns) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		for i, pattern := range patterns {
+			if strings.HasPrefix(pattern, "{") && strings.HasSuffix(pattern, "}") && segments[i] != "" {
+				params[strings.Trim(pattern, "{}")] = segments[i]
+			} else if pattern != segments[i] {
+				params = nil
+				break
+			}
+		}
+
+		if params != nil {
+			return route, params, true
+		}
+	}
+	return SEORoute{}, nil, false
+}
+
+const (
+	headUndecided = iota
+	headPassthrough
+	headBuffering
+	headDone
+)
+
+// headInjectingWriter buffer html responses until </head>, then inject seo tags and stream the rest
+type headInjectingWriter struct {
+	http.ResponseWriter
+	render func() template.HTML
+	status int
+	state  int
+	buffer bytes.Buffer
+}
+
+func (w *headInjectingWriter) WriteHeader(status int) {
+	if w.state != headUndecided {
+		return
+	}
+
+	w.status = status
+	if status != http.StatusOK || w.Header().Get("Content-Type") != "" {
+		w.decide(nil)
+	}
+}
+
+func (w *headInjectingWriter) Write(b []byte) (int, error) {
+	switch w.state {
+	case headUndecided:
+		w.decide(b)
+		return w.Write(b)
+	case headBuffering:
+		w.buffer.Write(b)
+		if loc := headEndRegexp.FindIndex(w.buffer.Bytes()); loc != nil {
+			w.state = headDone
+			if _, err := w.ResponseWriter.Write(w.inject(w.buffer.Bytes(), loc[0])); err != nil {
+				return 0, err
+			}
+		} else if w.buffer.Len() > maxHeadSize {
+			w.state = headDone
+			if _, err := w.ResponseWriter.Write(w.buffer.Bytes()); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	default:
+		return w.ResponseWriter.Write(b)
+	}
+}
+
+// Flush flush written content, html responses are flushed after injected
+func (w *headInjectingWriter) Flush() {
+	if w.state == headBuffering {
+		return
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.state == headUndecided {
+			w.decide(nil)
+		}
+		flusher.Flush()
+	}
+}
+
+// decide whether the response should be injected from its status, content type and the first written bytes
+func (w *headInjectingWriter) decide(b []byte) {
+	contentType := w.Header().Get("Content-Type")
+	if contentType == "" && len(b) > 0 {
+		contentType = http.DetectContentType(b)
+	}
+
+	if w.status == http.StatusOK && strings.HasPrefix(contentType, "text/html") && w.Header().Get("Content-Encoding") == "" {
+		w.state = headBuffering
+		w.Header().Del("Content-Length")
+	} else {
+		w.state = headPassthrough
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// inject replace seo tags in head before headEnd
+func (w *headInjectingWriter) inject(content []byte, headEnd int) []byte {
+	headStart := 0
+	if loc := headStartRegexp.FindIndex(content[:headEnd]); loc != nil {
+		headStart = loc[1]
+	}
+
+	tags := w.render()
+	rendered := map[string]bool{}
+	for _, match := range headMetaRegexp.FindAllStringSubmatch(string(tags), -1) {
+		rendered[strings.ToLower(match[1])] = true
+	}
+
+	head := headTitleRegexp.ReplaceAll(content[headStart:headEnd], nil)
+	head = headMetaRegexp.ReplaceAllFunc(head, func(tag []byte) []byte {
+		if isInjectedMeta(string(headMetaRegexp.FindSubmatch(tag)[1]), rendered) {
+			return nil
+		}
+		return tag
+	})
+
+	var result bytes.Buffer
+	result.Write(content[:headStart])
+	result.Write(head)
+	result.WriteString(string(tags))
+	result.Write(content[headEnd:])
+	return result.Bytes()
+}
+
+// finish write buffered content if </head> is never written
+func (w *headInjectingWriter) finish() {
+	switch w.state {
+	case headUndecided:
+		w.decide(nil)
+	case headBuffering:
+		w.state = headDone
+		w.ResponseWriter.Write(w.buffer.Bytes())
+	}
+}
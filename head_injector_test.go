@@ -0,0 +1,119 @@
+package seo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+func TestHeadInjector(t *testing.T) {
+	setupSeoCollection()
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} - {{Name}}", Description: "Shop {{Name}}"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/categories/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("<!DOCTYPE html><html><HEAD><meta charset=\"utf-8\">\n<title>Old Title</title>\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("<meta name=\"description\" content=\"old\"><meta property=\"og:title\" content=\"old\">\n<link rel=\"stylesheet\" href=\"/app.css\"></he"))
+		w.Write([]byte("ad><body><title>svg title</title></body></html>"))
+	})
+	mux.HandleFunc("/categories/api", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "15")
+		w.Write([]byte(`{"title":"api"}`))
+	})
+
+	injector := &HeadInjector{Collection: collection, DB: db, Routes: []SEORoute{
+		{Path: "/categories/api", SEO: "DefaultPage"},
+		{Path: "/categories/{Code}", SEO: "CategoryPage", Objects: func(req *http.Request, params map[string]string) []interface{} {
+			return []interface{}{strings.ToUpper(params["Code"][:1]) + params["Code"][1:]}
+		}},
+	}}
+	handler := injector.Middleware(mux)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/categories/shoes", nil))
+	body := w.Body.String()
+
+	for _, expect := range []string{"<title>Qor - Shoes</title>", `content="Shop Shoes"`, `<meta charset="utf-8">`, `<link rel="stylesheet" href="/app.css">`, "<body><title>svg title</title></body>"} {
+		if !strings.Contains(body, expect) {
+			t.Errorf("response should contains %v, but got %v", expect, body)
+		}
+	}
+
+	for _, unexpected := range []string{"Old Title", `content="old"`} {
+		if strings.Contains(body, unexpected) {
+			t.Errorf("response should not contains %v, but got %v", unexpected, body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/categories/api", nil))
+	if w.Body.String() != `{"title":"api"}` || w.Header().Get("Content-Length") != "15" {
+		t.Errorf("non html responses should not be changed, but got %v %v", w.Header(), w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/categories/shoes/reviews", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<title>Old Title</title>") {
+		t.Errorf("requests that don't match routes should not be changed, but got %v", w.Body.String())
+	}
+}
+
+type InjectedGlobalSetting struct {
+	SiteName    string
+	TwitterSite string `seo:"tag:twitter:site"`
+	FacebookApp string `seo:"tag:fb:app_id"`
+	ThemeColor  string `seo:"tag:theme-color"`
+	AppLinks
+	SiteVerification
+}
+
+func TestHeadInjectorReplacedTags(t *testing.T) {
+	setupSeoCollection()
+	collection = New("Seo")
+	collection.RegisterGlobalVaribles(&InjectedGlobalSetting{})
+	collection.RegisterSEO(&SEO{Name: "ProductPage", AppLinks: &AppLinkConfig{IOSURL: "qor://products"}})
+	Admin = admin.New(&qor.Config{DB: db})
+	Admin.AddResource(collection, &admin.Config{Name: "SEO Setting"})
+	Admin.MountTo("/admin", http.NewServeMux())
+
+	db.Create(&QorSEOSetting{Name: "Seo", IsGlobalSEO: true, Setting: Setting{GlobalSetting: map[string]string{
+		"SiteName": "Qor", "TwitterSite": "@qor", "FacebookApp": "123", "ThemeColor": "#000", "IOSAppStoreID": "456", "YandexVerification": "0123456789abcdef",
+	}}})
+	db.Create(&QorSEOSetting{Name: "ProductPage", Setting: Setting{Title: "Product", OpenGraphType: "product", OpenGraphObject: OpenGraphObject{Product: OpenGraphProduct{PriceAmount: "10", PriceCurrency: "USD"}}}})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/products", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`<html><head><meta name="viewport" content="width=device-width">
+<meta name="twitter:site" content="old"><meta name="twitter:card" content="app"><meta property="fb:app_id" content="old">
+<meta property="al:ios:url" content="old"><meta name="apple-itunes-app" content="old"><meta name="yandex-verification" content="old">
+<meta name="google-site-verification" content="app"><meta property="product:price:amount" content="old"><meta name="theme-color" content="old">
+<meta property="og:image" content="app"><meta property="og:image:width" content="app"><meta property="product:brand" content="app"><meta name="twitter:creator" content="app">
+</head><body></body></html>`))
+	})
+
+	injector := &HeadInjector{Collection: collection, DB: db, Routes: []SEORoute{{Path: "/products", SEO: "ProductPage"}}}
+	w := httptest.NewRecorder()
+	injector.Middleware(mux).ServeHTTP(w, httptest.NewRequest("GET", "/products", nil))
+	body := w.Body.String()
+
+	if strings.Contains(body, `content="old"`) {
+		t.Errorf("existing tags rendered by seo should be removed, but got %v", body)
+	}
+
+	if strings.Count(body, `content="app"`) != 6 {
+		t.Errorf("tags of the application not rendered by seo should be kept, but got %v", body)
+	}
+
+	for _, expect := range []string{`<meta name="viewport" content="width=device-width">`, `content="@qor"`, `content="123"`, `content="#000"`, `content="qor://products"`, `content="app-id=456, app-argument=qor://products"`, `content="0123456789abcdef"`, `content="10"`} {
+		if strings.Count(body, expect) != 1 {
+			t.Errorf("response should contains %v once, but got %v", expect, body)
+		}
+	}
+}
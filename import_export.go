@@ -0,0 +1,411 @@
+package seo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/media/media_library"
+	"github.com/qor/qor"
+)
+
+// ExportedSetting a seo setting in export files
+type ExportedSetting struct {
+	Name        string
+	IsGlobalSEO bool
+	Setting     Setting
+}
+
+// ImportConflict how to handle imported settings that would overwrite different existing settings
+type ImportConflict string
+
+// Import conflict strategies
+const (
+	ImportOverwrite ImportConflict = "overwrite"
+	ImportSkip      ImportConflict = "skip"
+	ImportFail      ImportConflict = "fail"
+)
+
+// ImportOptions options for importing seo settings
+type ImportOptions struct {
+	// DryRun only validate and compare imported settings, nothing will be saved
+	DryRun bool
+	// Conflict default is ImportOverwrite
+	Conflict ImportConflict
+}
+
+// Import actions
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionSkip      = "skip"
+	ImportActionConflict  = "conflict"
+)
+
+// ImportResult what happens, or would happen with dry run, to an imported setting
+type ImportResult struct {
+	Name    string
+	Action  string
+	Changes []SettingChange
+	Errors  []string
+}
+
+// ErrImportFailed returned when imported settings are invalid or conflict, nothing is saved in that case
+var ErrImportFailed = errors.New("seo: import failed, check errors of results")
+
+var exportCSVHeader = []string{
+	"Name", "IsGlobalSEO", "Title", "Description", "Keywords",
+	"OpenGraphTitle", "OpenGraphDescription", "OpenGraphURL", "OpenGraphType", "OpenGraphImageURL",
+	"OpenGraphImageFromMediaLibrary", "OpenGraphMetadata", "EnabledCustomize", "GlobalSetting", "OpenGraphObject",
+	"Type", "CustomizedFields",
+}
+
+// optionalCSVColumns columns added after the first CSV format, files exported before them could still be imported,
+// type of settings in those files is their name
+var optionalCSVColumns = map[string]bool{"OpenGraphObject": true, "Type": true, "CustomizedFields": true}
+
+// Export return site-wide setting and settings of registered seos that have been saved
+func (collection *Collection) Export(context *qor.Context) (settings []ExportedSetting, err error) {
+	db := context.GetDB()
+	for _, name := range collection.settingNames() {
+		result := collection.SettingResource.NewStruct()
+		if err = db.Where("name = ?", name).First(result).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				err = nil
+				continue
+			}
+			return nil, err
+		}
+
+		setting := result.(QorSEOSettingInterface)
+		settings = append(settings, ExportedSetting{Name: setting.GetName(), IsGlobalSEO: setting.GetIsGlobalSEO(), Setting: setting.GetSEOSetting()})
+	}
+	return settings, nil
+}
+
+// Import validate imported settings, compare them with existing settings and save them unless it is a dry run.
+// Settings are saved only when all of them are valid, and there is no conflict with ImportFail strategy
+func (collection *Collection) Import(context *qor.Context, settings []ExportedSetting, options ImportOptions) ([]ImportResult, error) {
+	if options.Conflict == "" {
+		options.Conflict = ImportOverwrite
+	}
+
+	var (
+		db      = context.GetDB()
+		failed  bool
+		results []ImportResult
+		records []interface{}
+	)
+
+	for _, imported := range settings {
+		result := ImportResult{Name: imported.Name, Errors: collection.validateImportedSetting(imported)}
+		record := collection.SettingResource.NewStruct()
+
+		if db.Where("name = ?", imported.Name).First(record).RecordNotFound() {
+			result.Action = ImportActionCreate
+			result.Changes = diffSettings(Setting{}, imported.Setting)
+		} else if result.Changes = diffSettings(record.(QorSEOSettingInterface).GetSEOSetting(), imported.Setting); len(result.Changes) == 0 {
+			result.Action = ImportActionUnchanged
+		} else {
+			switch options.Conflict {
+			case ImportSkip:
+				result.Action = ImportActionSkip
+			case ImportFail:
+				result.Action = ImportActionConflict
+				result.Errors = append(result.Errors, fmt.Sprintf("setting %v is different from the existing one", imported.Name))
+			default:
+				result.Action = ImportActionUpdate
+			}
+		}
+
+		// skipped settings are not saved, so their errors don't fail the import
+		if len(result.Errors) > 0 && result.Action != ImportActionSkip {
+			failed = true
+		}
+
+		if result.Action == ImportActionCreate || result.Action == ImportActionUpdate {
+			seoSetting := record.(QorSEOSettingInterface)
+			seoSetting.SetName(imported.Name)
+			seoSetting.SetIsGlobalSEO(imported.IsGlobalSEO)
+			if err := setSEOSetting(record, imported.Setting); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+				failed = true
+			}
+			records = append(records, record)
+		}
+
+		results = append(results, result)
+	}
+
+	if failed {
+		return results, ErrImportFailed
+	}
+
+	if options.DryRun {
+		return results, nil
+	}
+
+	tx := db.Begin()
+	txContext := context.Clone()
+	txContext.DB = tx
+	for _, record := range records {
+		if err := tx.Save(record).Error; err != nil {
+			tx.Rollback()
+			return results, err
+		}
+
+		if err := collection.saveVersion(txContext, record.(QorSEOSettingInterface), 0); err != nil {
+			tx.Rollback()
+			return results, err
+		}
+	}
+
+	return results, tx.Commit().Error
+}
+
+// settingNames return names of site-wide setting and registered seos
+func (collection *Collection) settingNames() []string {
+	names := []string{collection.Name}
+	for _, seo := range collection.registeredSEO {
+		names = append(names, seo.Name)
+	}
+	return names
+}
+
+// globalSettingFields return field names of registered site-wide setting
+func (collection *Collection) globalSettingFields() (fields []string) {
+	if collection.globalSetting != nil {
+		value := reflect.Indirect(reflect.ValueOf(collection.globalSetting))
+		for _, field := range globalSettingStructFields(value.Type()) {
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+// validateImportedSetting check the setting is registered and only uses variables that are available for it
+func (collection *Collection) validateImportedSetting(imported ExportedSetting) (errs []string) {
+	if imported.IsGlobalSEO {
+		if imported.Name != collection.Name {
+			return []string{fmt.Sprintf("site-wide setting should be named %v, but got %v", collection.Name, imported.Name)}
+		}
+
+		fields := map[string]bool{}
+		for _, field := range collection.globalSettingFields() {
+			fields[field] = true
+		}
+
+		for key := range imported.Setting.GlobalSetting {
+			if !fields[key] {
+				errs = append(errs, fmt.Sprintf("site-wide setting %v is not defined", key))
+			}
+		}
+
+		for _, provider := range VerificationProviders {
+			if err := provider.validate(imported.Setting.GlobalSetting[provider.Field]); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		return errs
+	}
+
+	var seo *SEO
+	for _, s := range collection.registeredSEO {
+		if s.Name == imported.Name {
+			seo = s
+		}
+	}
+
+	if seo == nil {
+		return []string{fmt.Sprintf("seo %v is not registered", imported.Name)}
+	}
+
+	validTags := map[string]bool{}
+	for _, tag := range append(collection.globalSettingFields(), seo.Varibles...) {
+		validTags[tag] = true
+	}
+
+	setting := imported.Setting
+	values := []string{
+		setting.Title, setting.Description, setting.Keywords,
+		setting.OpenGraphTitle, setting.OpenGraphDescription, setting.OpenGraphURL, setting.OpenGraphType, setting.OpenGraphImageURL,
+	}
+	for _, metadata := range setting.OpenGraphMetadata {
+		values = append(values, metadata.Property, metadata.Content)
+	}
+	values = append(values, setting.OpenGraphObject.values()...)
+
+	if err := setting.validateOpenGraphProperties(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	for _, value := range values {
+		for _, match := range variableRegexp.FindAllStringSubmatch(value, -1) {
+			if !validTags[match[1]] {
+				errs = append(errs, fmt.Sprintf("variable %v is not available for %v", match[0], seo.Name))
+			}
+		}
+	}
+	return errs
+}
+
+// WriteSettingsJSON write settings as JSON
+func WriteSettingsJSON(w io.Writer, settings []ExportedSetting) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(settings)
+}
+
+// ReadSettingsJSON read settings from JSON
+func ReadSettingsJSON(r io.Reader) (settings []ExportedSetting, err error) {
+	err = json.NewDecoder(r).Decode(&settings)
+	return settings, err
+}
+
+// WriteSettingsCSV write settings as CSV, one setting per row, open graph image, metadata, site-wide setting, properties of open graph types and customized fields are encoded as JSON
+func WriteSettingsCSV(w io.Writer, settings []ExportedSetting) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportCSVHeader); err != nil {
+		return err
+	}
+
+	for _, s := range settings {
+		setting := s.Setting
+		image, _ := json.Marshal(setting.OpenGraphImageFromMediaLibrary.Files)
+		metadata, _ := json.Marshal(setting.OpenGraphMetadata)
+		globalSetting, _ := json.Marshal(setting.GlobalSetting)
+		object, _ := json.Marshal(setting.OpenGraphObject)
+		customizedFields, _ := json.Marshal(setting.CustomizedFields)
+
+		if err := writer.Write([]string{
+			s.Name, strconv.FormatBool(s.IsGlobalSEO), setting.Title, setting.Description, setting.Keywords,
+			setting.OpenGraphTitle, setting.OpenGraphDescription, setting.OpenGraphURL, setting.OpenGraphType, setting.OpenGraphImageURL,
+			string(image), string(metadata), strconv.FormatBool(setting.EnabledCustomize), string(globalSetting), string(object),
+			setting.Type, string(customizedFields),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadSettingsCSV read settings from CSV written by WriteSettingsCSV
+func ReadSettingsCSV(r io.Reader) (settings []ExportedSetting, err error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for idx, column := range rows[0] {
+		columns[column] = idx
+	}
+
+	for _, column := range exportCSVHeader {
+		if _, ok := columns[column]; !ok && !optionalCSVColumns[column] {
+			return nil, fmt.Errorf("seo: column %v is missing", column)
+		}
+	}
+
+	for line, row := range rows[1:] {
+		get := func(column string) string {
+			if idx, ok := columns[column]; ok {
+				return row[idx]
+			}
+			return ""
+		}
+
+		var (
+			s     = ExportedSetting{Name: get("Name"), Setting: Setting{Type: get("Name")}}
+			files []media_library.File
+			errs  []error
+		)
+
+		s.IsGlobalSEO, err = strconv.ParseBool(get("IsGlobalSEO"))
+		errs = append(errs, err)
+		s.Setting.EnabledCustomize, err = strconv.ParseBool(get("EnabledCustomize"))
+		errs = append(errs, err)
+		errs = append(errs, unmarshalCSVColumn(get("OpenGraphImageFromMediaLibrary"), &files))
+		errs = append(errs, unmarshalCSVColumn(get("OpenGraphMetadata"), &s.Setting.OpenGraphMetadata))
+		errs = append(errs, unmarshalCSVColumn(get("GlobalSetting"), &s.Setting.GlobalSetting))
+		errs = append(errs, unmarshalCSVColumn(get("OpenGraphObject"), &s.Setting.OpenGraphObject))
+		errs = append(errs, unmarshalCSVColumn(get("CustomizedFields"), &s.Setting.CustomizedFields))
+
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("seo: line %v: %v", line+2, err)
+			}
+		}
+
+		s.Setting.Title = get("Title")
+		s.Setting.Description = get("Description")
+		s.Setting.Keywords = get("Keywords")
+		s.Setting.OpenGraphTitle = get("OpenGraphTitle")
+		s.Setting.OpenGraphDescription = get("OpenGraphDescription")
+		s.Setting.OpenGraphURL = get("OpenGraphURL")
+		s.Setting.OpenGraphType = get("OpenGraphType")
+		s.Setting.OpenGraphImageURL = get("OpenGraphImageURL")
+		s.Setting.OpenGraphImageFromMediaLibrary.Files = files
+		if _, ok := columns["Type"]; ok {
+			s.Setting.Type = get("Type")
+		}
+		settings = append(settings, s)
+	}
+
+	return settings, nil
+}
+
+func unmarshalCSVColumn(value string, result interface{}) error {
+	if value == "" || value == "null" {
+		return nil
+	}
+	return json.Unmarshal([]byte(value), result)
+}
+
+// ReadSettings read settings from JSON or CSV according to format
+func ReadSettings(r io.Reader, format string) ([]ExportedSetting, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return ReadSettingsCSV(r)
+	case "json":
+		return ReadSettingsJSON(r)
+	}
+	return nil, fmt.Errorf("seo: unsupported format %v", format)
+}
+
+// WriteSettings write settings as JSON or CSV according to format
+func WriteSettings(w io.Writer, settings []ExportedSetting, format string) error {
+	switch strings.ToLower(format) {
+	case "csv":
+		return WriteSettingsCSV(w, settings)
+	case "json":
+		return WriteSettingsJSON(w, settings)
+	}
+	return fmt.Errorf("seo: unsupported format %v", format)
+}
+
+// ExportURL get url to download settings in given format, json or csv
+func (collection *Collection) ExportURL(format string) string {
+	qorAdmin := collection.resource.GetAdmin()
+	return fmt.Sprintf("%v/%v/!seo_settings/export?format=%v", qorAdmin.GetRouter().Prefix, collection.resource.ToParam(), url.QueryEscape(format))
+}
+
+// ImportURL get url of import page
+func (collection *Collection) ImportURL() string {
+	qorAdmin := collection.resource.GetAdmin()
+	return fmt.Sprintf("%v/%v/!seo_settings/import", qorAdmin.GetRouter().Prefix, collection.resource.ToParam())
+}
@@ -0,0 +1,166 @@
+package seo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qor/qor"
+)
+
+func TestExportAndImportSettings(t *testing.T) {
+	setupSeoCollection()
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} {{Name}}", Type: "CategoryPage", CustomizedFields: []string{"Title"}, OpenGraphMetadata: []OpenGraphMetadata{{Property: "og:locale", Content: "en_US"}}})
+	context := &qor.Context{DB: db}
+
+	settings, err := collection.Export(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(settings) != 2 || !settings[0].IsGlobalSEO || settings[0].Setting.GlobalSetting["SiteName"] != "Qor" || settings[1].Name != "CategoryPage" {
+		t.Fatalf("should export site-wide setting and saved page settings, but got %#v", settings)
+	}
+
+	for _, format := range []string{"json", "csv"} {
+		var buf bytes.Buffer
+		if err := WriteSettings(&buf, settings, format); err != nil {
+			t.Fatal(err)
+		}
+
+		imported, err := ReadSettings(&buf, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(imported) != len(settings) {
+			t.Fatalf("%v: should read all exported settings, but got %#v", format, imported)
+		}
+
+		for i := range settings {
+			if imported[i].Name != settings[i].Name || imported[i].IsGlobalSEO != settings[i].IsGlobalSEO || len(diffSettings(settings[i].Setting, imported[i].Setting)) != 0 {
+				t.Errorf("%v: exported setting should be read back, expect %#v, but got %#v", format, settings[i], imported[i])
+			}
+		}
+	}
+
+	settings[1].Setting.Title = "{{SiteName}} - {{Name}}"
+	settings = append(settings, ExportedSetting{Name: "DefaultPage", Setting: Setting{Title: "{{BrandName}}"}})
+
+	results, err := collection.Import(context, settings, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := []string{ImportActionUnchanged, ImportActionUpdate, ImportActionCreate}
+	for i, result := range results {
+		if result.Action != actions[i] {
+			t.Errorf("import action of %v should be %v, but got %v", result.Name, actions[i], result.Action)
+		}
+	}
+
+	if changes := results[1].Changes; len(changes) != 1 || changes[0].Field != "Title" || changes[0].After != "{{SiteName}} - {{Name}}" {
+		t.Errorf("dry run should report changes, but got %#v", changes)
+	}
+
+	var setting QorSEOSetting
+	if db.First(&setting, "name = ?", "CategoryPage"); setting.Setting.Title != "{{SiteName}} {{Name}}" {
+		t.Errorf("dry run should not save settings, but got %v", setting.Setting.Title)
+	}
+
+	if results, err = collection.Import(context, settings, ImportOptions{Conflict: ImportFail}); err != ErrImportFailed || results[1].Action != ImportActionConflict {
+		t.Errorf("should fail when there are conflicts, but got %v %#v", err, results)
+	}
+
+	if results, err = collection.Import(context, settings, ImportOptions{Conflict: ImportSkip, DryRun: true}); err != nil || results[1].Action != ImportActionSkip {
+		t.Errorf("should skip conflicts, but got %v %#v", err, results)
+	}
+
+	conflicts := []ExportedSetting{{Name: "CategoryPage", Setting: Setting{Title: "{{Unknown}}"}}}
+	if results, err = collection.Import(context, conflicts, ImportOptions{Conflict: ImportSkip}); err != nil || results[0].Action != ImportActionSkip || len(results[0].Errors) != 1 {
+		t.Errorf("errors of skipped conflicts should be reported without failing the import, but got %v %#v", err, results)
+	}
+
+	invalid := []ExportedSetting{
+		{Name: "CategoryPage", Setting: Setting{Title: "{{Unknown}}"}},
+		{Name: "UnknownPage"},
+		{Name: "Seo", IsGlobalSEO: true, Setting: Setting{GlobalSetting: map[string]string{"Unknown": "value"}}},
+	}
+	if results, err = collection.Import(context, invalid, ImportOptions{}); err != ErrImportFailed {
+		t.Errorf("should fail with invalid settings, but got %v", err)
+	}
+	for _, result := range results {
+		if len(result.Errors) != 1 {
+			t.Errorf("setting %v should be invalid, but got %#v", result.Name, result.Errors)
+		}
+	}
+
+	if _, err = collection.Import(context, settings, ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	setting = QorSEOSetting{}
+	if db.First(&setting, "name = ?", "CategoryPage"); setting.Setting.Title != "{{SiteName}} - {{Name}}" {
+		t.Errorf("setting should be imported, but got %v", setting.Setting.Title)
+	}
+
+	if versions, _ := collection.Versions(context, "DefaultPage"); len(versions) != 1 {
+		t.Errorf("imported setting should be saved as a version, but got %#v", versions)
+	}
+}
+
+func TestImportSettingsFromAdmin(t *testing.T) {
+	setupSeoCollection()
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("QorSEOImportDryRun", "false")
+	part, _ := writer.CreateFormFile("QorSEOImportFile", "settings.json")
+	WriteSettingsJSON(part, []ExportedSetting{{Name: "CategoryPage", Setting: Setting{Title: "{{Name}}"}}})
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+collection.ImportURL(), &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		Results []ImportResult
+		Errors  []string
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK || len(result.Results) != 1 || result.Results[0].Action != ImportActionCreate {
+		t.Errorf("should import settings, but got %v %#v", resp.StatusCode, result)
+	}
+
+	resp, err = http.Get(server.URL + collection.ExportURL("csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := io.ReadAll(resp.Body)
+	if settings, err := ReadSettingsCSV(bytes.NewReader(content)); err != nil || len(settings) != 1 || settings[0].Setting.Title != "{{Name}}" {
+		t.Errorf("should export settings as csv, but got %v %v", err, string(content))
+	}
+}
+
+func TestReadSettingsCSVWithoutOptionalColumns(t *testing.T) {
+	content := "Name,IsGlobalSEO,Title,Description,Keywords,OpenGraphTitle,OpenGraphDescription,OpenGraphURL,OpenGraphType,OpenGraphImageURL,OpenGraphImageFromMediaLibrary,OpenGraphMetadata,EnabledCustomize,GlobalSetting\n" +
+		"CategoryPage,false,{{Name}},,,,,,,,,,false,\n"
+
+	settings, err := ReadSettingsCSV(strings.NewReader(content))
+	if err != nil || len(settings) != 1 || settings[0].Setting.Type != "CategoryPage" || settings[0].Setting.CustomizedFields != nil {
+		t.Errorf("csv exported by older versions should be read, type is the setting name, but got %v %#v", err, settings)
+	}
+}
@@ -0,0 +1,186 @@
+package seo
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/qor"
+	"github.com/qor/qor/utils"
+)
+
+// maxInheritanceDepth limit parents walked with SEO.Parent, to stop records that are parents of each other
+const maxInheritanceDepth = 10
+
+// settingLevel a level of the inheritance chain, a record's Setting field or a page setting
+type settingLevel struct {
+	SEO     string
+	Record  interface{}
+	Setting Setting
+	isPage  bool
+}
+
+// String return label of the level, e.g: `Category Page (Shoes)` for records, `Product Page` for page settings
+func (level settingLevel) String() string {
+	if level.isPage || level.Record == nil {
+		return level.SEO
+	}
+	return fmt.Sprintf("%v (%v)", level.SEO, utils.Stringify(level.Record))
+}
+
+// provides check if the level provides value of the field, page settings provide all fields
+func (level settingLevel) provides(field string) bool {
+	return level.isPage || level.Setting.IsCustomized(field)
+}
+
+// inheritanceChain return settings the seo rendered with the record inherits from, the most specific one first:
+// the record's setting, customized settings of parents returned by SEO.Parent, then the page setting.
+// A *SettingNotFoundError is returned with other levels if the seo has no saved setting, loaded page settings are kept in cache if it is not nil
+func (collection Collection) inheritanceChain(context *qor.Context, cache *settingCache, seo *SEO, record interface{}, setting Setting) ([]settingLevel, error) {
+	levels := []settingLevel{{SEO: seo.Name, Record: record, Setting: setting}}
+
+	for depth, current := 0, seo; depth < maxInheritanceDepth && current.Parent != nil && !isNil(record) && !providesAll(levels); depth++ {
+		name, parent := current.Parent(record)
+		if isNil(parent) {
+			break
+		}
+
+		if parentSetting, ok := settingFieldFor(parent, name); ok {
+			levels = append(levels, settingLevel{SEO: name, Record: parent, Setting: parentSetting})
+		}
+		record, current = parent, collection.GetSEO(name)
+	}
+
+	if providesAll(levels) {
+		return levels, nil
+	}
+
+	level, err := collection.loadPageSetting(context, cache, seo.Name)
+	if err != nil {
+		if _, ok := err.(*SettingNotFoundError); ok {
+			return levels, err
+		}
+		return nil, err
+	}
+	return append(levels, level), nil
+}
+
+// settingCache page settings and site-wide values loaded once to resolve settings of many records, e.g: auditing
+type settingCache struct {
+	pages          map[string]cachedPageSetting
+	siteWide       map[string]string
+	siteWideLoaded bool
+}
+
+type cachedPageSetting struct {
+	level settingLevel
+	err   error
+}
+
+func newSettingCache() *settingCache {
+	return &settingCache{pages: map[string]cachedPageSetting{}}
+}
+
+// loadPageSetting load page setting of the seo with its draft in effect, return a *SettingNotFoundError if it is not saved
+func (collection Collection) loadPageSetting(context *qor.Context, cache *settingCache, name string) (settingLevel, error) {
+	if cache != nil {
+		if cached, ok := cache.pages[name]; ok {
+			return cached.level, cached.err
+		}
+	}
+
+	level, err := settingLevel{SEO: name, isPage: true}, error(nil)
+	pageSetting := collection.SettingResource.NewStruct().(QorSEOSettingInterface)
+	if err = context.GetDB().Where("name = ?", name).First(pageSetting).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			err = &SettingNotFoundError{Name: name}
+		} else {
+			err = fmt.Errorf("seo: failed to load setting of %v: %w", name, err)
+		}
+	} else {
+		level.Setting = pageSetting.GetSEOSetting()
+		if draft, ok := draftInEffect(context, pageSetting); ok {
+			level.Setting = draft
+		}
+
+		if level.Setting.decodeErr != nil {
+			err = level.Setting.decodeErr
+		}
+	}
+
+	if cache != nil {
+		cache.pages[name] = cachedPageSetting{level: level, err: err}
+	}
+	return level, err
+}
+
+// inheritedSetting a setting resolved from an inheritance chain, with levels its fields come from
+type inheritedSetting struct {
+	Setting Setting
+	sources map[string]settingLevel
+}
+
+// InheritedFrom return label of the level the field inherited from, blank if it is customized by the record or not set by any level
+func (inherited inheritedSetting) InheritedFrom(field string) string {
+	if level, ok := inherited.sources[field]; ok {
+		return level.String()
+	}
+	return ""
+}
+
+// resolveChain fill each field with value of the most specific level that provides it
+func resolveChain(levels []settingLevel) inheritedSetting {
+	var (
+		result  Setting
+		sources = map[string]settingLevel{}
+	)
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		level := levels[i]
+		if level.isPage {
+			result = level.Setting
+		} else {
+			result = level.Setting.inherit(result)
+		}
+
+		for _, field := range CustomizableFields {
+			if level.provides(field) {
+				if i == 0 {
+					delete(sources, field)
+				} else {
+					sources[field] = level
+				}
+			}
+		}
+	}
+	return inheritedSetting{Setting: result, sources: sources}
+}
+
+// providesAll check if all fields are provided by the levels, then further levels are not needed
+func providesAll(levels []settingLevel) bool {
+	for _, field := range CustomizableFields {
+		var provided bool
+		for _, level := range levels {
+			if provided = level.provides(field); provided {
+				break
+			}
+		}
+
+		if !provided {
+			return false
+		}
+	}
+	return true
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
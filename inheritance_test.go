@@ -0,0 +1,83 @@
+package seo
+
+import (
+	"testing"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+type InheritedCategory struct {
+	Name   string
+	Parent *InheritedCategory
+	SEO    Setting `seo:"type:CategoryPage"`
+}
+
+type InheritedProduct struct {
+	Name     string
+	Category *InheritedCategory
+	SEO      Setting `seo:"type:ProductPage"`
+}
+
+func setupInheritance() {
+	setupSeoCollection()
+	collection.GetSEO("CategoryPage").Parent = func(record interface{}) (string, interface{}) {
+		return "CategoryPage", record.(InheritedCategory).Parent.value()
+	}
+	collection.RegisterSEO(&SEO{
+		Name: "ProductPage",
+		Parent: func(record interface{}) (string, interface{}) {
+			return "CategoryPage", record.(InheritedProduct).Category.value()
+		},
+	})
+}
+
+func (category *InheritedCategory) value() interface{} {
+	if category == nil {
+		return nil
+	}
+	return *category
+}
+
+func TestInheritanceChain(t *testing.T) {
+	setupInheritance()
+	db.Create(&QorSEOSetting{Name: "ProductPage", Setting: Setting{Title: "Product", Description: "Product", Keywords: "Product", OpenGraphTitle: "Product"}})
+	context := &qor.Context{DB: db}
+
+	apparel := &InheritedCategory{Name: "Apparel", SEO: Setting{Keywords: "Apparel", Description: "Apparel", CustomizedFields: []string{"Keywords", "Description"}, EnabledCustomize: true}}
+	shoes := &InheritedCategory{Name: "Shoes", Parent: apparel, SEO: Setting{Description: "Shoes", CustomizedFields: []string{"Description"}, EnabledCustomize: true}}
+	product := InheritedProduct{Name: "Sneaker", Category: shoes, SEO: Setting{Title: "Sneaker", CustomizedFields: []string{"Title"}, EnabledCustomize: true}}
+
+	setting := collection.GetSEOSetting(context, "ProductPage", product)
+	if setting.Title != "Sneaker" || setting.Description != "Shoes" || setting.Keywords != "Apparel" || setting.OpenGraphTitle != "Product" {
+		t.Errorf("settings should be inherited from the closest level, but got %#v", setting)
+	}
+
+	inherited := seoInheritedSetting(&admin.Context{Context: context}, collection.GetSEO("ProductPage"), &product, product.SEO)
+	for field, level := range map[string]string{"Title": "", "Description": "CategoryPage (Shoes)", "Keywords": "CategoryPage (Apparel)", "OpenGraphTitle": "ProductPage"} {
+		if from := inherited.InheritedFrom(field); from != level {
+			t.Errorf("%v should be inherited from %q, but got %q", field, level, from)
+		}
+	}
+
+	if setting := collection.GetSEOSetting(context, "ProductPage", InheritedProduct{Name: "Sneaker"}); setting.Title != "Product" || setting.Description != "Product" {
+		t.Errorf("records without parent should inherit page setting, but got %#v", setting)
+	}
+}
+
+func TestInheritanceChainWithCycle(t *testing.T) {
+	setupInheritance()
+	context := &qor.Context{DB: db}
+
+	shoes := &InheritedCategory{Name: "Shoes", SEO: Setting{Description: "Shoes", CustomizedFields: []string{"Description"}, EnabledCustomize: true}}
+	shoes.Parent = shoes
+
+	setting, err := collection.GetSEOSettingE(context, "ProductPage", InheritedProduct{Name: "Sneaker", Category: shoes})
+	if _, ok := err.(*SettingNotFoundError); !ok {
+		t.Errorf("not found error should be returned for missing page setting, but got %v", err)
+	}
+
+	if setting.Description != "Shoes" {
+		t.Errorf("description should be inherited from category, but got %#v", setting)
+	}
+}
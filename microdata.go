@@ -2,6 +2,7 @@ package seo
 
 import (
 	"bytes"
+	"fmt"
 	"html/template"
 )
 
@@ -59,14 +60,23 @@ func (contact MicroContact) Render() template.HTML {
 	return renderTemplate(MicroContactTemplate, contact)
 }
 
+// renderTemplate render structured data, errors are logged instead of written into pages
 func renderTemplate(content string, obj interface{}) template.HTML {
+	result, err := renderTemplateE(content, obj)
+	if err != nil {
+		logError(nil, err)
+	}
+	return result
+}
+
+func renderTemplateE(content string, obj interface{}) (template.HTML, error) {
 	tmpl, err := template.New("").Parse(content)
 	if err == nil {
 		var results bytes.Buffer
 		if err = tmpl.Execute(&results, obj); err == nil {
-			return template.HTML(results.String())
+			return template.HTML(results.String()), nil
 		}
 	}
 
-	return template.HTML(err.Error())
+	return "", &TemplateError{Name: fmt.Sprintf("%T", obj), Err: err}
 }
@@ -0,0 +1,400 @@
+package seo
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+// User agent classes of 404 logs
+const (
+	UserAgentBot     = "bot"
+	UserAgentBrowser = "browser"
+	UserAgentOther   = "other"
+)
+
+// QorSEONotFound a path that responded with 404, with how many times it is requested
+type QorSEONotFound struct {
+	ID             uint   `gorm:"primary_key"`
+	Path           string `gorm:"size:255;unique_index"`
+	Referrer       string `gorm:"size:255"`
+	UserAgentClass string
+	Hits           int
+	FirstSeenAt    time.Time
+	LastSeenAt     time.Time
+}
+
+// Defaults of NotFoundLogger
+var (
+	DefaultNotFoundMaxPathLength = 255
+	DefaultNotFoundMaxLogs       = 10000
+	DefaultNotFoundQueueSize     = 1000
+)
+
+// NotFoundLogger an http middleware that records requests responded with 404
+type NotFoundLogger struct {
+	DB *gorm.DB
+	// MaxPathLength paths and referrers are truncated to it, default is DefaultNotFoundMaxPathLength
+	MaxPathLength int
+	// MaxLogs new paths are not recorded when there are so many logs, hits of recorded paths are still counted, default is DefaultNotFoundMaxLogs
+	MaxLogs int
+	// IgnoreBots don't record requests of bots
+	IgnoreBots bool
+	// QueueSize requests are recorded in background by Middleware, requests are dropped when the queue is full, default is DefaultNotFoundQueueSize
+	QueueSize int
+	// ErrorHandler handle errors of recording requests in background, errors are logged if it is nil
+	ErrorHandler func(error)
+
+	mutex  sync.Mutex
+	queue  chan notFoundRequest
+	done   chan struct{}
+	closed bool
+}
+
+type notFoundRequest struct {
+	path, referrer, userAgent string
+}
+
+// NewNotFoundLogger initialize a 404 logger that saves logs into db
+func NewNotFoundLogger(db *gorm.DB) *NotFoundLogger {
+	return &NotFoundLogger{DB: db}
+}
+
+// Middleware serve requests with handler, and record GET and HEAD requests that responded with 404 in background, call Close to flush them
+func (logger *NotFoundLogger) Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writer := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(writer, req)
+
+		if writer.status == http.StatusNotFound && (req.Method == "GET" || req.Method == "HEAD") {
+			logger.enqueue(notFoundRequest{path: req.URL.Path, referrer: req.Referer(), userAgent: req.UserAgent()})
+		}
+	})
+}
+
+// enqueue add a request to the queue without blocking, start the recording goroutine for the first request
+func (logger *NotFoundLogger) enqueue(request notFoundRequest) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.closed {
+		return
+	}
+
+	if logger.queue == nil {
+		size := logger.QueueSize
+		if size <= 0 {
+			size = DefaultNotFoundQueueSize
+		}
+		logger.queue, logger.done = make(chan notFoundRequest, size), make(chan struct{})
+		go logger.run()
+	}
+
+	select {
+	case logger.queue <- request:
+	default:
+	}
+}
+
+func (logger *NotFoundLogger) run() {
+	defer close(logger.done)
+	for request := range logger.queue {
+		if err := logger.Record(request.path, request.referrer, request.userAgent); err != nil {
+			if logger.ErrorHandler != nil {
+				logger.ErrorHandler(err)
+			} else {
+				log.Printf("seo: failed to record 404 of %v, got %v", request.path, err)
+			}
+		}
+	}
+}
+
+// Close stop recording requests of Middleware, and wait for queued requests to be saved
+func (logger *NotFoundLogger) Close() error {
+	logger.mutex.Lock()
+	if logger.closed {
+		logger.mutex.Unlock()
+		return nil
+	}
+	logger.closed = true
+	queue, done := logger.queue, logger.done
+	if queue != nil {
+		close(queue)
+	}
+	logger.mutex.Unlock()
+
+	if done != nil {
+		<-done
+	}
+	return nil
+}
+
+// Record increase hits of a not found path
+func (logger *NotFoundLogger) Record(path, referrer, userAgent string) error {
+	var (
+		now   = time.Now()
+		class = userAgentClass(userAgent)
+	)
+
+	if logger.IgnoreBots && class == UserAgentBot {
+		return nil
+	}
+
+	maxLength := logger.MaxPathLength
+	if maxLength <= 0 {
+		maxLength = DefaultNotFoundMaxPathLength
+	}
+	path, referrer = truncateString(path, maxLength), truncateString(referrer, maxLength)
+
+	update := func() *gorm.DB {
+		return logger.DB.Model(&QorSEONotFound{}).Where("path = ?", path).UpdateColumns(map[string]interface{}{
+			"hits":             gorm.Expr("hits + ?", 1),
+			"referrer":         referrer,
+			"user_agent_class": class,
+			"last_seen_at":     now,
+		})
+	}
+
+	result := update()
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	maxLogs := logger.MaxLogs
+	if maxLogs <= 0 {
+		maxLogs = DefaultNotFoundMaxLogs
+	}
+
+	var count int
+	if err := logger.DB.Model(&QorSEONotFound{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= maxLogs {
+		return nil
+	}
+
+	log := QorSEONotFound{Path: path, Referrer: referrer, UserAgentClass: class, Hits: 1, FirstSeenAt: now, LastSeenAt: now}
+	if err := logger.DB.Create(&log).Error; err != nil {
+		// the path might be created by another request at the same time
+		if result := update(); result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		return err
+	}
+	return nil
+}
+
+// truncateString truncate str to at most length bytes, without breaking multi-byte characters
+func truncateString(str string, length int) string {
+	if len(str) <= length {
+		return str
+	}
+
+	str = str[:length]
+	for len(str) > 0 && !utf8.ValidString(str) {
+		str = str[:len(str)-1]
+	}
+	return str
+}
+
+// userAgentClass classify user agents as bots, browsers and others
+func userAgentClass(userAgent string) string {
+	userAgent = strings.ToLower(userAgent)
+	for _, keyword := range []string{"bot", "crawl", "spider", "slurp", "facebookexternalhit"} {
+		if strings.Contains(userAgent, keyword) {
+			return UserAgentBot
+		}
+	}
+
+	if strings.HasPrefix(userAgent, "mozilla/") || strings.HasPrefix(userAgent, "opera/") {
+		return UserAgentBrowser
+	}
+	return UserAgentOther
+}
+
+// SuggestRedirectTarget find the url most similar to path from sitemap sources of registered seos, return false if there is no similar one
+func (collection *Collection) SuggestRedirectTarget(context *qor.Context, path string) (string, bool, error) {
+	urls, err := collection.SitemapURLs(context)
+	if err != nil {
+		return "", false, err
+	}
+
+	target, ok := suggestRedirectTarget(urls, path)
+	return target, ok, nil
+}
+
+// suggestRedirectTarget find the url most similar to path from urls
+func suggestRedirectTarget(urls []SitemapURL, path string) (string, bool) {
+	var (
+		best      string
+		bestScore = 0.5
+	)
+	for _, sitemapURL := range urls {
+		candidate := sitemapURL.Loc
+		if u, err := url.Parse(candidate); err == nil && u.Path != "" {
+			candidate = u.Path
+		}
+
+		if candidate == path {
+			continue
+		}
+
+		if score := pathSimilarity(path, candidate); score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best, best != ""
+}
+
+// pathSimilarity score similarity of two paths between 0 and 1, by their shared words and edit distance
+func pathSimilarity(a, b string) float64 {
+	split := func(path string) map[string]bool {
+		words := map[string]bool{}
+		for _, word := range strings.FieldsFunc(strings.ToLower(path), func(r rune) bool { return strings.ContainsRune("/-_.", r) }) {
+			words[word] = true
+		}
+		return words
+	}
+
+	wordsA, wordsB := split(a), split(b)
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+
+	var wordScore float64
+	if total := len(wordsA) + len(wordsB) - shared; total > 0 {
+		wordScore = float64(shared) / float64(total)
+	}
+
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+
+	var editScore float64
+	if longest > 0 {
+		editScore = 1 - float64(levenshtein(a, b))/float64(longest)
+	}
+	return (wordScore + editScore) / 2
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func configureNotFoundResource(res *admin.Resource) {
+	res.IndexAttrs("Path", "Hits", "UserAgentClass", "Referrer", "FirstSeenAt", "LastSeenAt")
+	res.ShowAttrs("Path", "Hits", "UserAgentClass", "Referrer", "FirstSeenAt", "LastSeenAt")
+	res.SearchAttrs("Path", "Referrer")
+	res.Scope(&admin.Scope{Default: true, Handler: func(db *gorm.DB, context *qor.Context) *gorm.DB {
+		return db.Order("hits DESC")
+	}})
+
+	res.Action(&admin.Action{
+		Name:  "Create Redirect",
+		Label: "Redirect to Suggestion",
+		Handler: func(argument *admin.ActionArgument) error {
+			// sitemap sources of all collections are loaded once for all selected logs
+			var urls []SitemapURL
+			for _, collection := range collectionsOf(argument.Context.Admin) {
+				collectionURLs, err := collection.SitemapURLs(argument.Context.Context)
+				if err != nil {
+					return err
+				}
+				urls = append(urls, collectionURLs...)
+			}
+
+			var skipped []string
+			err := argument.Context.GetDB().Transaction(func(tx *gorm.DB) error {
+				for _, record := range argument.FindSelectedRecords() {
+					log := record.(*QorSEONotFound)
+					target, ok := suggestRedirectTarget(urls, log.Path)
+					if !ok {
+						skipped = append(skipped, log.Path)
+						continue
+					}
+
+					if err := tx.Create(&QorSEORedirect{Source: log.Path, Target: target}).Error; err != nil {
+						return err
+					}
+
+					if err := tx.Delete(log).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+			if err == nil && len(skipped) > 0 {
+				argument.Context.Flash(string(argument.Context.Admin.T(argument.Context.Context, "qor_seo.not_found.skipped", "No similar page is found for {{.}}, please create redirects manually", strings.Join(skipped, ", "))), "warning")
+			}
+			return err
+		},
+		Modes: []string{"menu_item", "show"},
+	})
+}
+
+// statusResponseWriter record status code of responses
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+func (w *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("seo: response writer doesn't support hijacking")
+}
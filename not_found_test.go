@@ -0,0 +1,148 @@
+package seo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/qor/qor"
+)
+
+func TestNotFoundLogger(t *testing.T) {
+	db.DropTableIfExists(&QorSEONotFound{})
+	db.AutoMigrate(&QorSEONotFound{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/found", func(w http.ResponseWriter, req *http.Request) { w.Write([]byte("found")) })
+	logger := NewNotFoundLogger(db)
+	handler := logger.Middleware(mux)
+
+	requests := []struct {
+		Path      string
+		Referrer  string
+		UserAgent string
+	}{
+		{Path: "/missing", UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"},
+		{Path: "/found", UserAgent: "Mozilla/5.0"},
+		{Path: "/missing", Referrer: "https://example.com/blog", UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)"},
+		{Path: "/gone", UserAgent: "curl/8.0"},
+	}
+
+	for _, r := range requests {
+		req := httptest.NewRequest("GET", r.Path, nil)
+		req.Header.Set("Referer", r.Referrer)
+		req.Header.Set("User-Agent", r.UserAgent)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	logger.Close()
+
+	var logs []QorSEONotFound
+	db.Order("hits DESC, path").Find(&logs)
+	if len(logs) != 2 {
+		t.Fatalf("should record 404 paths, but got %#v", logs)
+	}
+
+	if log := logs[0]; log.Path != "/missing" || log.Hits != 2 || log.Referrer != "https://example.com/blog" || log.UserAgentClass != UserAgentBrowser || log.LastSeenAt.Before(log.FirstSeenAt) {
+		t.Errorf("404 log of /missing is not correct, got %#v", log)
+	}
+
+	if log := logs[1]; log.Path != "/gone" || log.Hits != 1 || log.UserAgentClass != UserAgentOther {
+		t.Errorf("404 log of /gone is not correct, got %#v", log)
+	}
+
+	if class := userAgentClass("Mozilla/5.0 (compatible; bingbot/2.0)"); class != UserAgentBot {
+		t.Errorf("bingbot should be a bot, but got %v", class)
+	}
+}
+
+func TestNotFoundLoggerLimits(t *testing.T) {
+	db.DropTableIfExists(&QorSEONotFound{})
+	db.AutoMigrate(&QorSEONotFound{})
+
+	logger := &NotFoundLogger{DB: db, MaxPathLength: 10, MaxLogs: 2, IgnoreBots: true}
+	for _, path := range []string{"/products/sneaker", "/products/sneaker-2", "/about", "/contact"} {
+		if err := logger.Record(path, "", "Mozilla/5.0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger.Record("/bot", "", "Googlebot/2.1")
+
+	var logs []QorSEONotFound
+	db.Order("path").Find(&logs)
+	if len(logs) != 2 || logs[0].Path != "/about" || logs[1].Path != "/products/" || logs[1].Hits != 2 {
+		t.Errorf("paths should be truncated, and new paths should not be recorded over max logs, but got %#v", logs)
+	}
+
+	if str := truncateString("/café", 5); str != "/caf" {
+		t.Errorf("multi-byte characters should not be broken, but got %q", str)
+	}
+
+	db.DropTable(&QorSEONotFound{})
+	if err := logger.Record("/missing", "", "Mozilla/5.0"); err == nil {
+		t.Errorf("should return error if failed to save the log")
+	}
+}
+
+func TestSuggestRedirectTarget(t *testing.T) {
+	setupSeoCollection()
+	setupRedirects()
+	db.DropTableIfExists(&QorSEONotFound{})
+	db.AutoMigrate(&QorSEONotFound{})
+
+	collection.GetSEO("CategoryPage").Sitemap = func(*qor.Context) ([]SitemapURL, error) {
+		return []SitemapURL{{Loc: "https://example.com/categories/running-shoes"}, {Loc: "/categories/hats"}, {Loc: "/about"}}, nil
+	}
+
+	context := &qor.Context{DB: db}
+	cases := map[string]string{
+		"/categories/running-shoe": "/categories/running-shoes",
+		"/category/hats":           "/categories/hats",
+		"/about-us":                "/about",
+		"/checkout/payment":        "",
+	}
+	for path, expect := range cases {
+		if target, ok, err := collection.SuggestRedirectTarget(context, path); err != nil || target != expect || ok != (expect != "") {
+			t.Errorf("suggested target of %v should be %v, but got %v %v %v", path, expect, target, ok, err)
+		}
+	}
+
+	log := QorSEONotFound{Path: "/category/hats", Hits: 3}
+	db.Create(&log)
+	unmatched := QorSEONotFound{Path: "/checkout/payment", Hits: 1}
+	db.Create(&unmatched)
+
+	var sitemapLoads int
+	sitemap := collection.GetSEO("CategoryPage").Sitemap
+	collection.GetSEO("CategoryPage").Sitemap = func(context *qor.Context) ([]SitemapURL, error) {
+		sitemapLoads++
+		return sitemap(context)
+	}
+
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	res := collection.NotFoundResource
+	actionURL := fmt.Sprintf("%v/admin/%v/!action/%v", server.URL, res.ToParam(), res.GetAction("Create Redirect").ToParam())
+	if _, err := http.PostForm(actionURL, url.Values{"_method": {"PUT"}, "primary_values[]": {fmt.Sprint(unmatched.ID), fmt.Sprint(log.ID)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var redirect QorSEORedirect
+	if db.First(&redirect, "source = ?", "/category/hats"); redirect.Target != "/categories/hats" {
+		t.Errorf("redirect should be created to suggested target, but got %#v", redirect)
+	}
+
+	if !db.First(&QorSEONotFound{}, log.ID).RecordNotFound() {
+		t.Errorf("404 log should be removed after redirected")
+	}
+
+	if db.First(&QorSEONotFound{}, unmatched.ID).RecordNotFound() {
+		t.Errorf("404 log without suggestion should be skipped")
+	}
+
+	if sitemapLoads != 1 {
+		t.Errorf("sitemap urls should be loaded once for all selected logs, but loaded %v times", sitemapLoads)
+	}
+}
@@ -0,0 +1,189 @@
+package seo
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/admin"
+	"github.com/qor/media"
+	"github.com/qor/media/media_library"
+	"github.com/qor/qor"
+)
+
+// openGraphImageStyle name of the open graph image variant cropped with the size
+func openGraphImageStyle(size *media.Size) string {
+	return fmt.Sprintf("qor_seo_og_%vx%v", size.Width, size.Height)
+}
+
+// CropOpenGraphImage generate a variant of the open graph image selected from media library with OpenGraph.Size, which is rendered as og:image.
+// Images selected in admin are cropped when settings are saved
+func (seo *SEO) CropOpenGraphImage(db *gorm.DB, setting Setting) error {
+	mediaLibrary, err := seo.openGraphMedia(db, setting)
+	if err != nil || mediaLibrary == nil || seo.OpenGraph.Size == nil {
+		return err
+	}
+
+	style := openGraphImageStyle(seo.OpenGraph.Size)
+	if _, ok := mediaLibrary.GetMediaOption().Sizes[style]; ok {
+		return nil
+	}
+
+	mediaBox := media_library.MediaBox{Files: setting.OpenGraphImageFromMediaLibrary.Files[:1]}
+	if err := mediaBox.Crop(seo.OpenGraph.ImageResource, db, media_library.MediaOption{Sizes: map[string]*media.Size{style: seo.OpenGraph.Size}}); err != nil {
+		return fmt.Errorf("seo: failed to crop open graph image of %v: %w", seo.Name, err)
+	}
+	seo.OpenGraph.mediaOptions.Delete(string(mediaBox.Files[0].ID))
+	return nil
+}
+
+// cropOpenGraphImageOfField crop open graph image of a Setting field with size of its seo, used when records are saved in admin
+func cropOpenGraphImageOfField(qorAdmin *admin.Admin, record interface{}, fieldName string, db *gorm.DB) error {
+	field := reflect.Indirect(reflect.ValueOf(record)).FieldByName(fieldName)
+	if !field.IsValid() {
+		return nil
+	}
+
+	setting, ok := field.Interface().(Setting)
+	if !ok {
+		return nil
+	}
+
+	if seo := seoOfField(qorAdmin, record, fieldName); seo != nil {
+		return seo.CropOpenGraphImage(db, setting)
+	}
+	return nil
+}
+
+// openGraphMedia return media library record of the open graph image, nil if the image is not selected from media library or OpenGraph.ImageResource is not configured
+func (seo *SEO) openGraphMedia(db *gorm.DB, setting Setting) (media_library.MediaLibraryInterface, error) {
+	if seo.OpenGraph == nil || seo.OpenGraph.ImageResource == nil || len(setting.OpenGraphImageFromMediaLibrary.Files) == 0 {
+		return nil, nil
+	}
+
+	res := seo.OpenGraph.ImageResource
+	record := res.NewStruct()
+	if err := res.CallFindOne(record, nil, &qor.Context{ResourceID: string(setting.OpenGraphImageFromMediaLibrary.Files[0].ID), DB: db}); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("seo: failed to load open graph image of %v: %w", seo.Name, err)
+	}
+
+	mediaLibrary, ok := record.(media_library.MediaLibraryInterface)
+	if !ok {
+		return nil, fmt.Errorf("seo: open graph image resource of %v doesn't implement media_library.MediaLibraryInterface", seo.Name)
+	}
+	return mediaLibrary, nil
+}
+
+// OpenGraphMediaCacheTTL how long options of open graph images selected from media library are cached for rendering,
+// changes of the images made in media library are rendered after it, images cropped by CropOpenGraphImage are rendered immediately
+var OpenGraphMediaCacheTTL = 5 * time.Minute
+
+// cachedMediaOption media option of an open graph image, option is nil if the image is not found
+type cachedMediaOption struct {
+	option   *media_library.MediaOption
+	loadedAt time.Time
+}
+
+// openGraphMediaOption return media option of the open graph image, options are cached by media id for OpenGraphMediaCacheTTL
+func (seo *SEO) openGraphMediaOption(db *gorm.DB, setting Setting) (*media_library.MediaOption, error) {
+	if seo.OpenGraph == nil || seo.OpenGraph.ImageResource == nil || len(setting.OpenGraphImageFromMediaLibrary.Files) == 0 {
+		return nil, nil
+	}
+
+	id := string(setting.OpenGraphImageFromMediaLibrary.Files[0].ID)
+	if value, ok := seo.OpenGraph.mediaOptions.Load(id); ok {
+		if cached := value.(cachedMediaOption); time.Since(cached.loadedAt) < OpenGraphMediaCacheTTL {
+			return cached.option, nil
+		}
+	}
+
+	mediaLibrary, err := seo.openGraphMedia(db, setting)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := cachedMediaOption{loadedAt: time.Now()}
+	if mediaLibrary != nil {
+		option := mediaLibrary.GetMediaOption()
+		cached.option = &option
+	}
+	seo.OpenGraph.mediaOptions.Store(id, cached)
+	return cached.option, nil
+}
+
+// openGraphImageMetadata return og:image of the setting with its width, height, type and alt,
+// the image of OpenGraph.Generator is used if it is configured, otherwise the variant cropped with OpenGraph.Size if it is generated
+func (seo *SEO) openGraphImageMetadata(context *qor.Context, setting Setting) ([]OpenGraphMetadata, error) {
+	var (
+		imageURL, alt string
+		size          *media.Size
+	)
+
+	if seo.OpenGraph != nil && seo.OpenGraph.Generator != nil && setting.OpenGraphImageURL == "" {
+		generatedURL, err := seo.OpenGraph.Generator.imageURL(context, seo, setting)
+		if err != nil {
+			return nil, err
+		}
+
+		if alt = setting.OpenGraphTitle; alt == "" {
+			alt = setting.Title
+		}
+		return []OpenGraphMetadata{
+			{Property: "og:image", Content: absoluteURL(context, generatedURL)},
+			{Property: "og:image:width", Content: strconv.Itoa(generatedImageWidth)},
+			{Property: "og:image:height", Content: strconv.Itoa(generatedImageHeight)},
+			{Property: "og:image:type", Content: "image/png"},
+			{Property: "og:image:alt", Content: alt},
+		}, nil
+	}
+
+	if files := setting.OpenGraphImageFromMediaLibrary.Files; len(files) > 0 {
+		imageURL, alt = files[0].URL(), files[0].Description
+
+		option, err := seo.openGraphMediaOption(context.GetDB(), setting)
+		if err != nil {
+			return nil, err
+		}
+
+		if option != nil {
+			if alt == "" {
+				alt = option.Description
+			}
+
+			if seo.OpenGraph.Size != nil {
+				if _, ok := option.Sizes[openGraphImageStyle(seo.OpenGraph.Size)]; ok {
+					imageURL, size = files[0].URL(openGraphImageStyle(seo.OpenGraph.Size)), seo.OpenGraph.Size
+				}
+			}
+		}
+	} else if imageURL = setting.OpenGraphImageURL; imageURL == "" {
+		return nil, nil
+	}
+
+	metadata := []OpenGraphMetadata{{Property: "og:image", Content: absoluteURL(context, imageURL)}}
+	if size != nil {
+		metadata = append(metadata,
+			OpenGraphMetadata{Property: "og:image:width", Content: strconv.Itoa(size.Width)},
+			OpenGraphMetadata{Property: "og:image:height", Content: strconv.Itoa(size.Height)},
+		)
+	}
+
+	if u, err := url.Parse(imageURL); err == nil {
+		if typ := mime.TypeByExtension(path.Ext(u.Path)); typ != "" {
+			metadata = append(metadata, OpenGraphMetadata{Property: "og:image:type", Content: typ})
+		}
+	}
+
+	if alt != "" {
+		metadata = append(metadata, OpenGraphMetadata{Property: "og:image:alt", Content: alt})
+	}
+	return metadata, nil
+}
@@ -0,0 +1,488 @@
+package seo
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	// decoders of background and logo images
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/disintegration/imaging"
+	"github.com/qor/media/oss"
+	"github.com/qor/qor"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// size of generated open graph images
+const (
+	generatedImageWidth  = 1200
+	generatedImageHeight = 630
+	generatedImageMargin = 80
+)
+
+// OpenGraphImageGenerator generate 1200×630 PNG open graph images with the page's title over a background and a logo.
+// Set it to OpenGraphConfig.Generator of seos that use generated images, and mount it to Prefix to serve them:
+//
+//	generator := &seo.OpenGraphImageGenerator{Prefix: "/og-images/", Logo: logo}
+//	mux.Handle("/og-images/", generator)
+type OpenGraphImageGenerator struct {
+	// Prefix path the generator is mounted to
+	Prefix string
+	Logo   image.Image
+	// Font font of the title, Go Bold is used if it is nil
+	Font      *opentype.Font
+	TextColor color.Color
+	// BackgroundColor used if there is no background image
+	BackgroundColor color.Color
+	// Cache store generated images by hash of what they are generated from, images are saved into media storage and recent ones are kept in memory if it is nil,
+	// so they could be served after restarts and by other servers that share the storage. Changes of Font are not detected, clear the cache after changing it
+	Cache OpenGraphImageCache
+	// LoadImage load images selected from media library as background, images are read from media's storage if it is nil
+	LoadImage func(url string) (image.Image, error)
+	// ErrorHandler handle errors of generating images in background, errors are logged if it is nil
+	ErrorHandler func(error)
+
+	initOnce   sync.Once
+	face       font.Face
+	initErr    error
+	digests    sync.Map
+	mutex      sync.Mutex
+	rendered   *recentMap
+	generating map[string]*imageGeneration
+}
+
+// renderedImage what an image rendered in pages is generated from
+type renderedImage struct {
+	seo           *SEO
+	title         string
+	backgroundURL string
+	templateImage image.Image
+}
+
+// imageGeneration an image being generated, requests of the image wait for it
+type imageGeneration struct {
+	done chan struct{}
+	err  error
+}
+
+// OpenGraphImageCache cache of generated open graph images
+type OpenGraphImageCache interface {
+	Get(key string) ([]byte, bool)
+	// Has check an image is cached without reading it
+	Has(key string) bool
+	Set(key string, value []byte) error
+}
+
+// Defaults of OpenGraphImageGenerator
+var (
+	// DefaultOpenGraphImageMemoryCacheSize how many generated images are kept in memory by the default cache
+	DefaultOpenGraphImageMemoryCacheSize = 100
+	// DefaultOpenGraphImageRenderedSize how many images rendered in pages are remembered, so they could be generated when they are requested
+	DefaultOpenGraphImageRenderedSize = 10000
+)
+
+// OpenGraphImageStoragePath path of generated images in media storage, used by the default cache
+var OpenGraphImageStoragePath = "/system/qor_seo_og_images"
+
+// NewOpenGraphImageMemoryCache cache generated images in memory, the least recently used ones are removed when there are more than size images
+func NewOpenGraphImageMemoryCache(size int) OpenGraphImageCache {
+	return &memoryImageCache{images: newRecentMap(size)}
+}
+
+// recentMap a map that keeps size recently used values, 0 means unlimited
+type recentMap struct {
+	size   int
+	mutex  sync.Mutex
+	values map[string]*list.Element
+	recent *list.List
+}
+
+type recentEntry struct {
+	key   string
+	value interface{}
+}
+
+func newRecentMap(size int) *recentMap {
+	return &recentMap{size: size, values: map[string]*list.Element{}, recent: list.New()}
+}
+
+func (m *recentMap) get(key string) (interface{}, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if element, ok := m.values[key]; ok {
+		m.recent.MoveToFront(element)
+		return element.Value.(*recentEntry).value, true
+	}
+	return nil, false
+}
+
+func (m *recentMap) set(key string, value interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if element, ok := m.values[key]; ok {
+		element.Value.(*recentEntry).value = value
+		m.recent.MoveToFront(element)
+		return
+	}
+
+	m.values[key] = m.recent.PushFront(&recentEntry{key: key, value: value})
+	for m.recent.Len() > m.size && m.size > 0 {
+		oldest := m.recent.Back()
+		m.recent.Remove(oldest)
+		delete(m.values, oldest.Value.(*recentEntry).key)
+	}
+}
+
+// memoryImageCache a LRU cache of generated images
+type memoryImageCache struct {
+	images *recentMap
+}
+
+func (cache *memoryImageCache) Get(key string) ([]byte, bool) {
+	if value, ok := cache.images.get(key); ok {
+		return value.([]byte), true
+	}
+	return nil, false
+}
+
+func (cache *memoryImageCache) Has(key string) bool {
+	_, ok := cache.images.get(key)
+	return ok
+}
+
+func (cache *memoryImageCache) Set(key string, value []byte) error {
+	cache.images.set(key, value)
+	return nil
+}
+
+// storageImageCache default cache of generated images, images are saved into media storage, recent ones are kept in memory
+type storageImageCache struct {
+	memory OpenGraphImageCache
+}
+
+func (cache *storageImageCache) Get(key string) ([]byte, bool) {
+	if value, ok := cache.memory.Get(key); ok {
+		return value, true
+	}
+
+	file, err := oss.Storage.GetStream(cache.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	value, err := io.ReadAll(file)
+	if err != nil {
+		return nil, false
+	}
+	cache.memory.Set(key, value)
+	return value, true
+}
+
+// Has open the image in storage without reading it
+func (cache *storageImageCache) Has(key string) bool {
+	if cache.memory.Has(key) {
+		return true
+	}
+
+	file, err := oss.Storage.GetStream(cache.path(key))
+	if err != nil {
+		return false
+	}
+	file.Close()
+	return true
+}
+
+func (cache *storageImageCache) Set(key string, value []byte) error {
+	if _, err := oss.Storage.Put(cache.path(key), bytes.NewReader(value)); err != nil {
+		return err
+	}
+	return cache.memory.Set(key, value)
+}
+
+func (cache *storageImageCache) path(key string) string {
+	return strings.TrimSuffix(OpenGraphImageStoragePath, "/") + "/" + key + ".png"
+}
+
+// ServeHTTP serve generated images from the cache, images rendered in pages are generated if they are not generated yet
+func (generator *OpenGraphImageGenerator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if err := generator.init(); err != nil {
+		generator.handleError(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	key := strings.TrimSuffix(path.Base(req.URL.Path), ".png")
+	if !imageKeyRegexp.MatchString(key) {
+		http.NotFound(w, req)
+		return
+	}
+
+	data, ok := generator.Cache.Get(key)
+	if !ok {
+		value, rendered := generator.rendered.get(key)
+		if !rendered {
+			http.NotFound(w, req)
+			return
+		}
+
+		generation := generator.generate(key, value.(*renderedImage))
+		if <-generation.done; generation.err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if data, ok = generator.Cache.Get(key); !ok {
+			http.NotFound(w, req)
+			return
+		}
+	}
+
+	// key is the hash of what the image is generated from, so it never changes
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, req, key+".png", time.Time{}, bytes.NewReader(data))
+}
+
+// imageKeyRegexp keys of generated images are sha256 hashes of what they are generated from: title, background, template image, logo and colors
+var imageKeyRegexp = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// URL return url of the generated image with key
+func (generator *OpenGraphImageGenerator) URL(key string) string {
+	return strings.TrimSuffix(generator.Prefix, "/") + "/" + key + ".png"
+}
+
+// imageURL return url of the image of the setting rendered by the seo, images rendered for the first time are generated in background if they are not cached.
+// The setting's image selected from media library is used as background, then the seo's OpenGraph.TemplateImage
+func (generator *OpenGraphImageGenerator) imageURL(context *qor.Context, seo *SEO, setting Setting) (string, error) {
+	if err := generator.init(); err != nil {
+		return "", err
+	}
+
+	rendered := &renderedImage{seo: seo, title: setting.OpenGraphTitle}
+	if rendered.title == "" {
+		rendered.title = setting.Title
+	}
+
+	if files := setting.OpenGraphImageFromMediaLibrary.Files; len(files) > 0 {
+		rendered.backgroundURL = files[0].URL()
+	}
+
+	if seo.OpenGraph != nil {
+		rendered.templateImage = seo.OpenGraph.TemplateImage
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%q %q %v %v %v %v", rendered.title, rendered.backgroundURL, generator.digest(rendered.templateImage), generator.digest(generator.Logo), generator.TextColor, generator.BackgroundColor)
+	key := hex.EncodeToString(hash.Sum(nil))
+
+	// rendering never reads the cache, images that are failed or removed from the cache are generated again when they are requested
+	if _, ok := generator.rendered.get(key); !ok {
+		generator.rendered.set(key, rendered)
+		generator.generate(key, rendered)
+	}
+	return generator.URL(key), nil
+}
+
+// generate start generating the image in background if it is not being generated, different images are generated in parallel
+func (generator *OpenGraphImageGenerator) generate(key string, rendered *renderedImage) *imageGeneration {
+	generator.mutex.Lock()
+	defer generator.mutex.Unlock()
+
+	if generation, ok := generator.generating[key]; ok {
+		return generation
+	}
+
+	generation := &imageGeneration{done: make(chan struct{})}
+	generator.generating[key] = generation
+	go func() {
+		if generation.err = generator.generateImage(key, rendered); generation.err != nil {
+			generator.handleError(generation.err)
+		}
+
+		generator.mutex.Lock()
+		delete(generator.generating, key)
+		generator.mutex.Unlock()
+		close(generation.done)
+	}()
+	return generation
+}
+
+func (generator *OpenGraphImageGenerator) handleError(err error) {
+	if generator.ErrorHandler != nil {
+		generator.ErrorHandler(err)
+	} else {
+		log.Printf("seo: %v", err)
+	}
+}
+
+// generateImage compose the image and save it into cache with key, unless it is already cached
+func (generator *OpenGraphImageGenerator) generateImage(key string, rendered *renderedImage) error {
+	if generator.Cache.Has(key) {
+		return nil
+	}
+
+	background := rendered.templateImage
+	if rendered.backgroundURL != "" {
+		img, err := generator.LoadImage(rendered.backgroundURL)
+		if err != nil {
+			return fmt.Errorf("seo: failed to load open graph image background %v: %w", rendered.backgroundURL, err)
+		}
+		background = img
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, generator.compose(background, rendered.title)); err != nil {
+		return fmt.Errorf("seo: failed to encode open graph image of %v: %w", rendered.seo.Name, err)
+	}
+
+	if err := generator.Cache.Set(key, buf.Bytes()); err != nil {
+		return fmt.Errorf("seo: failed to cache open graph image of %v: %w", rendered.seo.Name, err)
+	}
+	return nil
+}
+
+// compose draw background, logo and title
+func (generator *OpenGraphImageGenerator) compose(background image.Image, title string) image.Image {
+	canvas := imaging.New(generatedImageWidth, generatedImageHeight, generator.BackgroundColor)
+	if background != nil {
+		canvas = imaging.Fill(background, generatedImageWidth, generatedImageHeight, imaging.Center, imaging.Lanczos)
+		// darken background to keep the title readable
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.NRGBA{A: 96}), image.Point{}, draw.Over)
+	}
+
+	if generator.Logo != nil {
+		logo := imaging.Resize(generator.Logo, 0, generatedImageMargin, imaging.Lanczos)
+		canvas = imaging.Overlay(canvas, logo, image.Pt(generatedImageMargin, generatedImageHeight-generatedImageMargin-logo.Bounds().Dy()), 1)
+	}
+
+	lines := wrapText(generator.face, title, generatedImageWidth-2*generatedImageMargin, 3)
+	lineHeight := generator.face.Metrics().Height.Ceil()
+	drawer := font.Drawer{Dst: canvas, Src: image.NewUniform(generator.TextColor), Face: generator.face}
+	top := (generatedImageHeight-lineHeight*len(lines))/2 + generator.face.Metrics().Ascent.Ceil()
+	for idx, line := range lines {
+		drawer.Dot = fixed.P(generatedImageMargin, top+idx*lineHeight)
+		drawer.DrawString(line)
+	}
+	return canvas
+}
+
+func (generator *OpenGraphImageGenerator) init() error {
+	generator.initOnce.Do(func() {
+		if generator.Cache == nil {
+			generator.Cache = &storageImageCache{memory: NewOpenGraphImageMemoryCache(DefaultOpenGraphImageMemoryCacheSize)}
+		}
+		generator.rendered = newRecentMap(DefaultOpenGraphImageRenderedSize)
+		generator.generating = map[string]*imageGeneration{}
+
+		if generator.TextColor == nil {
+			generator.TextColor = color.White
+		}
+
+		if generator.BackgroundColor == nil {
+			generator.BackgroundColor = color.NRGBA{R: 31, G: 41, B: 55, A: 255}
+		}
+
+		if generator.LoadImage == nil {
+			generator.LoadImage = loadMediaImage
+		}
+
+		if generator.Font == nil {
+			if generator.Font, generator.initErr = opentype.Parse(gobold.TTF); generator.initErr != nil {
+				return
+			}
+		}
+		generator.face, generator.initErr = opentype.NewFace(generator.Font, &opentype.FaceOptions{Size: 64, DPI: 72, Hinting: font.HintingFull})
+	})
+	return generator.initErr
+}
+
+// digest return hash of pixels of an image, it is calculated once for each image
+func (generator *OpenGraphImageGenerator) digest(img image.Image) string {
+	if img == nil {
+		return ""
+	}
+
+	comparable := reflect.TypeOf(img).Comparable()
+	if comparable {
+		if digest, ok := generator.digests.Load(img); ok {
+			return digest.(string)
+		}
+	}
+
+	hash := sha256.New()
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			hash.Write([]byte{byte(r >> 8), byte(g >> 8), byte(b >> 8), byte(a >> 8)})
+		}
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	if comparable {
+		generator.digests.Store(img, digest)
+	}
+	return digest
+}
+
+// wrapText split text into lines fit width, text over maxLines is cut with an ellipsis
+func wrapText(face font.Face, text string, width int, maxLines int) (lines []string) {
+	var line string
+	for _, word := range strings.Fields(text) {
+		if candidate := strings.TrimSpace(line + " " + word); line == "" || font.MeasureString(face, candidate).Ceil() <= width {
+			line = candidate
+			continue
+		}
+		lines = append(lines, line)
+		line = word
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1]
+		for last != "" && font.MeasureString(face, last+"…").Ceil() > width {
+			last = strings.TrimSpace(last[:strings.LastIndex(last, " ")+1])
+		}
+		lines[maxLines-1] = last + "…"
+	}
+	return lines
+}
+
+// loadMediaImage load image from storage of media library
+func loadMediaImage(url string) (image.Image, error) {
+	file, err := oss.OSS{}.Retrieve(url)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(io.Reader(file))
+	return img, err
+}
@@ -0,0 +1,267 @@
+package seo
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/qor/media/media_library"
+	"github.com/qor/media/oss"
+	"github.com/qor/oss/filesystem"
+	"github.com/qor/qor"
+)
+
+var generatedImageRegexp = regexp.MustCompile(`og:image" content="http://[^/]*(/og-images/[0-9a-f]+\.png)"`)
+
+func TestOpenGraphImageGenerator(t *testing.T) {
+	setupSeoCollection()
+	storage := oss.Storage
+	oss.Storage = filesystem.New(t.TempDir())
+	defer func() { oss.Storage = storage }()
+
+	var (
+		mutex  sync.Mutex
+		loaded []string
+	)
+	generator := &OpenGraphImageGenerator{
+		Prefix: "/og-images/",
+		Logo:   imaging.New(40, 20, color.White),
+		LoadImage: func(url string) (image.Image, error) {
+			mutex.Lock()
+			loaded = append(loaded, url)
+			mutex.Unlock()
+			return imaging.New(600, 600, color.NRGBA{R: 255, A: 255}), nil
+		},
+	}
+	collection.RegisterSEO(&SEO{Name: "ProductPage", OpenGraph: &OpenGraphConfig{Generator: generator, TemplateImage: imaging.New(100, 50, color.Black)}})
+	context := &qor.Context{DB: db}
+
+	db.Create(&QorSEOSetting{Name: "ProductPage", Setting: Setting{Title: "Sneaker"}})
+	result := string(collection.Render(context, "ProductPage"))
+	matches := generatedImageRegexp.FindStringSubmatch(result)
+	if len(matches) != 2 {
+		t.Fatalf("generated open graph image should be rendered, but got %v", result)
+	}
+
+	for _, tag := range []string{`og:image:width" content="1200"`, `og:image:height" content="630"`, `og:image:type" content="image/png"`, `og:image:alt" content="Sneaker"`} {
+		if !strings.Contains(result, tag) {
+			t.Errorf("%v not found in %v", tag, result)
+		}
+	}
+
+	if again := generatedImageRegexp.FindStringSubmatch(string(collection.Render(context, "ProductPage"))); len(again) != 2 || again[1] != matches[1] {
+		t.Errorf("same content should be rendered with same image, but got %v", again)
+	}
+
+	recorder := httptest.NewRecorder()
+	generator.ServeHTTP(recorder, httptest.NewRequest("GET", matches[1], nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("generated image should be served, but got %v %v", recorder.Code, recorder.Header())
+	}
+
+	img, err := png.Decode(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != 1200 || size.Y != 630 {
+		t.Errorf("generated image should be 1200×630, but got %v", size)
+	}
+
+	recorder = httptest.NewRecorder()
+	generator.ServeHTTP(recorder, httptest.NewRequest("GET", "/og-images/unknown.png", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("images not generated should not be found, but got %v", recorder.Code)
+	}
+
+	// images are saved into media storage, so they could be served by other servers or after restarts
+	recorder = httptest.NewRecorder()
+	(&OpenGraphImageGenerator{Prefix: "/og-images/"}).ServeHTTP(recorder, httptest.NewRequest("GET", matches[1], nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("generated image should be served by other generators sharing the storage, but got %v", recorder.Code)
+	}
+
+	product := InheritedProduct{SEO: Setting{
+		Title:                          "Red Sneaker",
+		OpenGraphImageFromMediaLibrary: media_library.MediaBox{Files: []media_library.File{{ID: "1", Url: "/system/red.jpg"}}},
+		EnabledCustomize:               true,
+	}}
+	changed := generatedImageRegexp.FindStringSubmatch(string(collection.Render(context, "ProductPage", product)))
+	if len(changed) != 2 || changed[1] == matches[1] {
+		t.Fatalf("changed content should be rendered with a new image, but got %v", changed)
+	}
+
+	recorder = httptest.NewRecorder()
+	generator.ServeHTTP(recorder, httptest.NewRequest("GET", changed[1], nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("changed image should be served, but got %v", recorder.Code)
+	}
+
+	if mutex.Lock(); len(loaded) != 1 || loaded[0] != "/system/red.jpg" {
+		t.Errorf("image selected from media library should be used as background, but loaded %v", loaded)
+	}
+	mutex.Unlock()
+}
+
+func TestOpenGraphImageGeneratorConcurrency(t *testing.T) {
+	var (
+		mutex  sync.Mutex
+		loaded = map[string]int{}
+	)
+	generator := &OpenGraphImageGenerator{
+		Prefix: "/og-images/",
+		Cache:  NewOpenGraphImageMemoryCache(10),
+		LoadImage: func(url string) (image.Image, error) {
+			mutex.Lock()
+			loaded[url]++
+			mutex.Unlock()
+			return imaging.New(600, 600, color.White), nil
+		},
+	}
+
+	seo := &SEO{Name: "ProductPage"}
+	urls := make([]string, 20)
+	var wg sync.WaitGroup
+	for i := range urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			background := fmt.Sprintf("/system/%v.jpg", i%2)
+			setting := Setting{Title: "Sneaker", OpenGraphImageFromMediaLibrary: media_library.MediaBox{Files: []media_library.File{{ID: "1", Url: background}}}}
+			urls[i], _ = generator.imageURL(&qor.Context{}, seo, setting)
+
+			recorder := httptest.NewRecorder()
+			generator.ServeHTTP(recorder, httptest.NewRequest("GET", urls[i], nil))
+			if recorder.Code != http.StatusOK {
+				t.Errorf("generated image should be served, but got %v", recorder.Code)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(loaded) != 2 || loaded["/system/0.jpg"] != 1 || loaded["/system/1.jpg"] != 1 {
+		t.Errorf("each image should be generated once, but loaded backgrounds %v", loaded)
+	}
+
+	for i, url := range urls {
+		if url == "" || url != urls[i%2] {
+			t.Errorf("same content should get the same image, but got %v", urls)
+			break
+		}
+	}
+}
+
+type countingImageCache struct {
+	OpenGraphImageCache
+	gets int32
+}
+
+func (cache *countingImageCache) Get(key string) ([]byte, bool) {
+	atomic.AddInt32(&cache.gets, 1)
+	return cache.OpenGraphImageCache.Get(key)
+}
+
+func TestOpenGraphImageGeneratorInBackground(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		errs    = make(chan error, 1)
+		cache   = &countingImageCache{OpenGraphImageCache: NewOpenGraphImageMemoryCache(10)}
+	)
+	generator := &OpenGraphImageGenerator{
+		Prefix: "/og-images/",
+		Cache:  cache,
+		LoadImage: func(url string) (image.Image, error) {
+			<-release
+			if url == "/system/broken.jpg" {
+				return nil, errors.New("broken image")
+			}
+			return imaging.New(600, 600, color.White), nil
+		},
+		ErrorHandler: func(err error) { errs <- err },
+	}
+
+	seo := &SEO{Name: "ProductPage"}
+	settingWithBackground := func(background string) Setting {
+		return Setting{Title: "Sneaker", OpenGraphImageFromMediaLibrary: media_library.MediaBox{Files: []media_library.File{{ID: "1", Url: background}}}}
+	}
+
+	rendered := make(chan string)
+	go func() {
+		url, _ := generator.imageURL(&qor.Context{}, seo, settingWithBackground("/system/sneaker.jpg"))
+		rendered <- url
+	}()
+
+	var url string
+	select {
+	case url = <-rendered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("rendering should not wait for generating images")
+	}
+
+	if gets := atomic.LoadInt32(&cache.gets); gets != 0 {
+		t.Errorf("rendering should not read the cache, but got %v reads", gets)
+	}
+
+	close(release)
+	recorder := httptest.NewRecorder()
+	generator.ServeHTTP(recorder, httptest.NewRequest("GET", url, nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("image should be served once it is generated, but got %v", recorder.Code)
+	}
+
+	url, _ = generator.imageURL(&qor.Context{}, seo, settingWithBackground("/system/broken.jpg"))
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "broken image") {
+		t.Errorf("errors of generating images in background should be passed to ErrorHandler, but got %v", err)
+	}
+
+	recorder = httptest.NewRecorder()
+	generator.ServeHTTP(recorder, httptest.NewRequest("GET", url, nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("images failed to generate should not be served, but got %v", recorder.Code)
+	}
+}
+
+func TestOpenGraphImageMemoryCache(t *testing.T) {
+	cache := NewOpenGraphImageMemoryCache(2)
+	cache.Set("a", []byte("a"))
+	cache.Set("b", []byte("b"))
+	cache.Get("a")
+	cache.Set("c", []byte("c"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("least recently used image should be removed")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if value, ok := cache.Get(key); !ok || string(value) != key {
+			t.Errorf("recently used image %v should be kept, but got %v", key, value)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	generator := &OpenGraphImageGenerator{}
+	if err := generator.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := wrapText(generator.face, "Sneaker", 1040, 3); len(lines) != 1 || lines[0] != "Sneaker" {
+		t.Errorf("short text should not be wrapped, but got %v", lines)
+	}
+
+	lines := wrapText(generator.face, strings.Repeat("Comfortable Running Sneaker ", 10), 1040, 3)
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], "…") {
+		t.Errorf("long text should be wrapped into 3 lines with an ellipsis, but got %v", lines)
+	}
+}
@@ -0,0 +1,105 @@
+package seo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/media"
+	"github.com/qor/media/media_library"
+	"github.com/qor/media/oss"
+	"github.com/qor/qor"
+)
+
+func createOpenGraphImage(description string) (*media_library.MediaLibrary, Setting) {
+	db.DropTableIfExists(&media_library.MediaLibrary{})
+	db.AutoMigrate(&media_library.MediaLibrary{})
+
+	image := media_library.MediaLibrary{SelectedType: "image"}
+	image.File = media_library.MediaLibraryStorage{OSS: oss.OSS{Base: media.Base{Url: "/system/media_libraries/1/file.jpg"}}, Description: description}
+	db.Create(&image)
+
+	mediaBox := media_library.MediaBox{Files: []media_library.File{{ID: json.Number(fmt.Sprint(image.ID)), Url: image.File.URL()}}}
+	return &image, Setting{Title: "Sneaker", OpenGraphImageFromMediaLibrary: mediaBox}
+}
+
+func TestCropOpenGraphImage(t *testing.T) {
+	setupSeoCollection()
+	size := &media.Size{Width: 1200, Height: 630}
+	collection.RegisterSEO(&SEO{Name: "ProductPage", OpenGraph: &OpenGraphConfig{ImageResource: Admin.NewResource(&media_library.MediaLibrary{}), Size: size}})
+	image, setting := createOpenGraphImage("Sneakers")
+
+	if err := collection.GetSEO("ProductPage").CropOpenGraphImage(db, setting); err != nil {
+		t.Fatal(err)
+	}
+
+	var cropped media_library.MediaLibrary
+	db.First(&cropped, image.ID)
+	if croppedSize := cropped.GetMediaOption().Sizes[openGraphImageStyle(size)]; croppedSize == nil || *croppedSize != *size {
+		t.Errorf("open graph image should be cropped with %v, but got %#v", size, cropped.GetMediaOption().Sizes)
+	}
+}
+
+func TestRenderOpenGraphImage(t *testing.T) {
+	setupSeoCollection()
+	size := &media.Size{Width: 1200, Height: 630}
+	collection.RegisterSEO(&SEO{Name: "ProductPage", OpenGraph: &OpenGraphConfig{ImageResource: Admin.NewResource(&media_library.MediaLibrary{}), Size: size}})
+	context := &qor.Context{DB: db}
+
+	_, setting := createOpenGraphImage("Sneakers")
+	db.Create(&QorSEOSetting{Name: "ProductPage", Setting: setting})
+
+	queries := 0
+	db.Callback().Query().After("gorm:query").Register("seo_test:media", func(scope *gorm.Scope) {
+		if scope.TableName() == "media_libraries" {
+			queries++
+		}
+	})
+	defer db.Callback().Query().Remove("seo_test:media")
+
+	result := string(collection.Render(context, "ProductPage"))
+	for _, tag := range []string{`/system/media_libraries/1/file.jpg"`, `og:image:type" content="image/jpeg"`, `og:image:alt" content="Sneakers"`} {
+		if !strings.Contains(result, tag) {
+			t.Errorf("original open graph image should be rendered before it is cropped, %v not found in %v", tag, result)
+		}
+	}
+
+	if collection.Render(context, "ProductPage"); queries != 1 {
+		t.Errorf("media of open graph image should be cached, but got %v queries", queries)
+	}
+
+	if err := collection.GetSEO("ProductPage").CropOpenGraphImage(db, setting); err != nil {
+		t.Fatal(err)
+	}
+
+	result = string(collection.Render(context, "ProductPage"))
+	for _, tag := range []string{"file." + openGraphImageStyle(size) + ".jpg", `og:image:width" content="1200"`, `og:image:height" content="630"`, `og:image:alt" content="Sneakers"`} {
+		if !strings.Contains(result, tag) {
+			t.Errorf("cropped open graph image should be rendered, %v not found in %v", tag, result)
+		}
+	}
+}
+
+func TestRenderWithOpenGraphImageError(t *testing.T) {
+	setupSeoCollection()
+	collection.RegisterSEO(&SEO{Name: "ProductPage", OpenGraph: &OpenGraphConfig{ImageResource: Admin.NewResource(&media_library.MediaLibrary{})}})
+	context := &qor.Context{DB: db}
+
+	_, setting := createOpenGraphImage("Sneakers")
+	db.Create(&QorSEOSetting{Name: "ProductPage", Setting: setting})
+
+	db.Callback().Query().After("gorm:query").Register("seo_test:fail", func(scope *gorm.Scope) {
+		if scope.TableName() == "media_libraries" {
+			scope.Err(errors.New("failed"))
+		}
+	})
+	defer db.Callback().Query().Remove("seo_test:fail")
+
+	result, err := collection.RenderE(context, "ProductPage")
+	if err == nil || !strings.Contains(string(result), "<title>Sneaker</title>") || strings.Contains(string(result), "og:image") {
+		t.Errorf("tags except og:image should be rendered with the error, but got %v, %v", err, result)
+	}
+}
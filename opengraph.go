@@ -0,0 +1,100 @@
+package seo
+
+import "strings"
+
+// OpenGraphProperties ordered open graph properties, properties could be repeated as arrays, e.g: og:locale:alternate,
+// structured properties like og:image:width follow the og:image they describe
+type OpenGraphProperties []OpenGraphMetadata
+
+// structuredOpenGraphProperties properties that could have structured properties
+var structuredOpenGraphProperties = []string{"og:image", "og:video", "og:audio"}
+
+// structuredRootOf return the property a structured property describes, e.g: og:image of og:image:width
+func structuredRootOf(property string) (string, bool) {
+	for _, root := range structuredOpenGraphProperties {
+		if strings.HasPrefix(property, root+":") {
+			return root, true
+		}
+	}
+	return "", false
+}
+
+// Get return content of the first property
+func (properties OpenGraphProperties) Get(property string) string {
+	for _, p := range properties {
+		if p.Property == property {
+			return p.Content
+		}
+	}
+	return ""
+}
+
+// GetAll return contents of the property
+func (properties OpenGraphProperties) GetAll(property string) (contents []string) {
+	for _, p := range properties {
+		if p.Property == property {
+			contents = append(contents, p.Content)
+		}
+	}
+	return contents
+}
+
+// Add add a property, structured properties like og:image:width describe the last og:image, and replace the same structured property of it
+func (properties OpenGraphProperties) Add(property, content string) OpenGraphProperties {
+	root, ok := structuredRootOf(property)
+	if !ok {
+		return append(properties, OpenGraphMetadata{Property: property, Content: content})
+	}
+
+	start := -1
+	for idx := len(properties) - 1; idx >= 0; idx-- {
+		if properties[idx].Property == root {
+			start = idx
+			break
+		}
+	}
+
+	if start < 0 {
+		return append(properties, OpenGraphMetadata{Property: property, Content: content})
+	}
+
+	end := start + 1
+	for ; end < len(properties); end++ {
+		if r, ok := structuredRootOf(properties[end].Property); !ok || r != root {
+			break
+		}
+
+		if properties[end].Property == property {
+			result := append(OpenGraphProperties{}, properties...)
+			result[end].Content = content
+			return result
+		}
+	}
+
+	result := make(OpenGraphProperties, 0, len(properties)+1)
+	result = append(result, properties[:end]...)
+	result = append(result, OpenGraphMetadata{Property: property, Content: content})
+	return append(result, properties[end:]...)
+}
+
+// Merge return properties overridden by others, properties of others replace the same properties with structured properties describe them,
+// e.g: og:image of others replace og:image with its og:image:width, og:image:width of others only replace og:image:width
+func (properties OpenGraphProperties) Merge(others []OpenGraphMetadata) OpenGraphProperties {
+	overridden := map[string]bool{}
+	for _, other := range others {
+		overridden[other.Property] = true
+	}
+
+	var result OpenGraphProperties
+	for _, p := range properties {
+		if root, ok := structuredRootOf(p.Property); overridden[p.Property] || (ok && overridden[root]) {
+			continue
+		}
+		result = append(result, p)
+	}
+
+	for _, other := range others {
+		result = result.Add(other.Property, other.Content)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package seo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qor/qor"
+)
+
+func TestOpenGraphPropertiesMerge(t *testing.T) {
+	defaults := OpenGraphProperties{
+		{Property: "og:image", Content: "/default.jpg"},
+		{Property: "og:image:alt", Content: "Default"},
+		{Property: "og:title", Content: "Title"},
+	}
+
+	testCases := []struct {
+		Metadata []OpenGraphMetadata
+		Expected OpenGraphProperties
+	}{
+		{
+			Metadata: []OpenGraphMetadata{{Property: "og:image:alt", Content: "Shoes"}, {Property: "og:locale:alternate", Content: "ja_JP"}, {Property: "og:locale:alternate", Content: "zh_CN"}},
+			Expected: OpenGraphProperties{{Property: "og:image", Content: "/default.jpg"}, {Property: "og:image:alt", Content: "Shoes"}, {Property: "og:title", Content: "Title"}, {Property: "og:locale:alternate", Content: "ja_JP"}, {Property: "og:locale:alternate", Content: "zh_CN"}},
+		},
+		{
+			Metadata: []OpenGraphMetadata{{Property: "og:image", Content: "/a.jpg"}, {Property: "og:title", Content: "Custom"}, {Property: "og:image", Content: "/b.jpg"}, {Property: "og:image:width", Content: "400"}, {Property: "og:image:alt", Content: "A"}},
+			Expected: OpenGraphProperties{{Property: "og:image", Content: "/a.jpg"}, {Property: "og:title", Content: "Custom"}, {Property: "og:image", Content: "/b.jpg"}, {Property: "og:image:width", Content: "400"}, {Property: "og:image:alt", Content: "A"}},
+		},
+	}
+
+	for i, testCase := range testCases {
+		if result := defaults.Merge(testCase.Metadata); !reflect.DeepEqual(result, testCase.Expected) {
+			t.Errorf("#%v: expected %v, but got %v", i+1, testCase.Expected, result)
+		}
+	}
+
+	if images := defaults.Merge(testCases[1].Metadata).GetAll("og:image"); !reflect.DeepEqual(images, []string{"/a.jpg", "/b.jpg"}) {
+		t.Errorf("repeated properties should be kept, but got %v", images)
+	}
+
+	if defaults[1].Content != "Default" {
+		t.Errorf("merge should not change properties, but got %v", defaults)
+	}
+}
+
+func TestFormattedHTMLWithStructuredProperties(t *testing.T) {
+	setting := Setting{
+		Title:             "Sneaker",
+		OpenGraphImageURL: "http://qor.test/sneaker.jpg",
+		OpenGraphMetadata: []OpenGraphMetadata{
+			{Property: "og:image:width", Content: "1200"},
+			{Property: "og:image", Content: "http://qor.test/sneaker-side.jpg"},
+			{Property: "og:image:width", Content: "600"},
+		},
+	}
+
+	result := string(setting.FormattedHTML(&qor.Context{}))
+	expected := []string{
+		`<meta property="og:image" name="og:image" content="http://qor.test/sneaker-side.jpg">`,
+		`<meta property="og:image:width" name="og:image:width" content="600">`,
+	}
+	if !strings.Contains(result, strings.Join(expected, "\n")) {
+		t.Errorf("og:image of metadata should replace default image with its structured properties, but got %v", result)
+	}
+
+	setting.OpenGraphMetadata = setting.OpenGraphMetadata[:1]
+	result = string(setting.FormattedHTML(&qor.Context{}))
+	expected = []string{
+		`<meta property="og:image" name="og:image" content="http://qor.test/sneaker.jpg">`,
+		`<meta property="og:image:width" name="og:image:width" content="1200">`,
+		`<meta property="og:title" name="og:title" content="Sneaker">`,
+	}
+	if !strings.Contains(result, strings.Join(expected, "\n")) {
+		t.Errorf("structured properties should follow the image they describe, but got %v", result)
+	}
+}
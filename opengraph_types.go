@@ -0,0 +1,201 @@
+package seo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// OpenGraphVocabularies properties allowed for vertical open graph types, properties of these namespaces in OpenGraphMetadata
+// are validated against the og:type of the setting, e.g: product:price:amount is only allowed for product
+var OpenGraphVocabularies = map[string][]string{
+	"product": {
+		"product:price:amount", "product:price:currency", "product:availability", "product:condition",
+		"product:brand", "product:retailer_item_id",
+	},
+	"video.movie": {
+		"video:actor", "video:actor:role", "video:director", "video:writer", "video:duration", "video:release_date", "video:tag",
+	},
+	"video.episode": {
+		"video:actor", "video:actor:role", "video:director", "video:writer", "video:duration", "video:release_date", "video:tag",
+		"video:series",
+	},
+	"music.song": {
+		"music:duration", "music:album", "music:album:disc", "music:album:track", "music:musician",
+	},
+	"book": {
+		"book:author", "book:isbn", "book:release_date", "book:tag",
+	},
+}
+
+// OpenGraphObject properties of vertical open graph types, only properties of the og:type are rendered.
+// Values could use variables like other fields, e.g: {{Price}} filled by SEO.Context, lists are separated by commas
+type OpenGraphObject struct {
+	Product OpenGraphProduct
+	Video   OpenGraphVideo
+	Song    OpenGraphSong
+	Book    OpenGraphBook
+}
+
+// OpenGraphProduct properties of og:type product
+type OpenGraphProduct struct {
+	PriceAmount    string
+	PriceCurrency  string
+	Availability   string
+	Condition      string
+	Brand          string
+	RetailerItemID string
+}
+
+// OpenGraphVideo properties of og:type video.movie and video.episode, Series is only rendered for episodes
+type OpenGraphVideo struct {
+	Actors      string
+	Directors   string
+	Writers     string
+	Duration    string
+	ReleaseDate string
+	Tags        string
+	Series      string
+}
+
+// OpenGraphSong properties of og:type music.song
+type OpenGraphSong struct {
+	Duration   string
+	Album      string
+	AlbumDisc  string
+	AlbumTrack string
+	Musicians  string
+}
+
+// OpenGraphBook properties of og:type book
+type OpenGraphBook struct {
+	Authors     string
+	ISBN        string
+	ReleaseDate string
+	Tags        string
+}
+
+// Properties return properties of the product
+func (product OpenGraphProduct) Properties() (properties OpenGraphProperties) {
+	return properties.
+		addValue("product:price:amount", product.PriceAmount).
+		addValue("product:price:currency", product.PriceCurrency).
+		addValue("product:availability", product.Availability).
+		addValue("product:condition", product.Condition).
+		addValue("product:brand", product.Brand).
+		addValue("product:retailer_item_id", product.RetailerItemID)
+}
+
+// Properties return properties of the video, video:series is rendered only if episode is true
+func (video OpenGraphVideo) Properties(episode bool) (properties OpenGraphProperties) {
+	properties = properties.
+		addList("video:actor", video.Actors).
+		addList("video:director", video.Directors).
+		addList("video:writer", video.Writers).
+		addValue("video:duration", video.Duration).
+		addValue("video:release_date", video.ReleaseDate).
+		addList("video:tag", video.Tags)
+
+	if episode {
+		properties = properties.addValue("video:series", video.Series)
+	}
+	return properties
+}
+
+// Properties return properties of the song
+func (song OpenGraphSong) Properties() (properties OpenGraphProperties) {
+	return properties.
+		addValue("music:duration", song.Duration).
+		addValue("music:album", song.Album).
+		addValue("music:album:disc", song.AlbumDisc).
+		addValue("music:album:track", song.AlbumTrack).
+		addList("music:musician", song.Musicians)
+}
+
+// Properties return properties of the book
+func (book OpenGraphBook) Properties() (properties OpenGraphProperties) {
+	return properties.
+		addList("book:author", book.Authors).
+		addValue("book:isbn", book.ISBN).
+		addValue("book:release_date", book.ReleaseDate).
+		addList("book:tag", book.Tags)
+}
+
+// Properties return properties of the open graph type, nil if it is not a vertical type
+func (object OpenGraphObject) Properties(openGraphType string) OpenGraphProperties {
+	switch openGraphType {
+	case "product":
+		return object.Product.Properties()
+	case "video.movie":
+		return object.Video.Properties(false)
+	case "video.episode":
+		return object.Video.Properties(true)
+	case "music.song":
+		return object.Song.Properties()
+	case "book":
+		return object.Book.Properties()
+	}
+	return nil
+}
+
+// values return values of all fields, used to check variables
+func (object OpenGraphObject) values() (values []string) {
+	value := reflect.ValueOf(object)
+	for i := 0; i < value.NumField(); i++ {
+		for j := 0; j < value.Field(i).NumField(); j++ {
+			values = append(values, value.Field(i).Field(j).String())
+		}
+	}
+	return values
+}
+
+// replace return the object with all fields replaced by fc, used to fill variables
+func (object OpenGraphObject) replace(fc func(string) string) OpenGraphObject {
+	value := reflect.ValueOf(&object).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		for j := 0; j < value.Field(i).NumField(); j++ {
+			field := value.Field(i).Field(j)
+			field.SetString(fc(field.String()))
+		}
+	}
+	return object
+}
+
+func (properties OpenGraphProperties) addValue(property, content string) OpenGraphProperties {
+	if content = strings.TrimSpace(content); content == "" {
+		return properties
+	}
+	return properties.Add(property, content)
+}
+
+func (properties OpenGraphProperties) addList(property, contents string) OpenGraphProperties {
+	for _, content := range strings.Split(contents, ",") {
+		properties = properties.addValue(property, content)
+	}
+	return properties
+}
+
+// validateOpenGraphProperties check properties of vertical open graph types in OpenGraphMetadata are allowed for og:type of the setting,
+// settings without og:type, or og:type filled by variables, are not validated as the type is unknown until rendered
+func (setting Setting) validateOpenGraphProperties() error {
+	openGraphType := setting.OpenGraphType
+	if openGraphType == "" || variableRegexp.MatchString(openGraphType) {
+		return nil
+	}
+
+	namespaces, allowed := map[string]bool{}, map[string]bool{}
+	for typ, properties := range OpenGraphVocabularies {
+		for _, property := range properties {
+			namespaces[strings.SplitN(property, ":", 2)[0]] = true
+			allowed[property] = allowed[property] || typ == openGraphType
+		}
+	}
+
+	for _, metadata := range setting.OpenGraphMetadata {
+		namespace := strings.SplitN(metadata.Property, ":", 2)[0]
+		if namespaces[namespace] && !allowed[metadata.Property] && !variableRegexp.MatchString(metadata.Property) {
+			return fmt.Errorf("property %v is not allowed for og:type %v", metadata.Property, openGraphType)
+		}
+	}
+	return nil
+}
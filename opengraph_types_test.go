@@ -0,0 +1,124 @@
+package seo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/qor/qor"
+)
+
+func TestOpenGraphTypeProperties(t *testing.T) {
+	setupSeoCollection()
+	collection.RegisterSEO(&SEO{
+		Name:     "MoviePage",
+		Varibles: []string{"Price", "Actors"},
+		Context: func(objects ...interface{}) map[string]string {
+			return map[string]string{"Price": "9.99", "Actors": "Alice, Bob"}
+		},
+	})
+	context := &qor.Context{DB: db}
+
+	object := OpenGraphObject{
+		Product: OpenGraphProduct{PriceAmount: "{{Price}}", PriceCurrency: "USD"},
+		Video:   OpenGraphVideo{Actors: "{{Actors}}", Duration: "5400", Series: "http://qor.test/series"},
+	}
+	db.Create(&QorSEOSetting{Name: "MoviePage", Setting: Setting{Title: "Movie", OpenGraphType: "video.movie", OpenGraphObject: object}})
+
+	result := string(collection.Render(context, "MoviePage"))
+	expected := strings.Join([]string{
+		`<meta property="og:type" name="og:type" content="video.movie">`,
+		`<meta property="video:actor" name="video:actor" content="Alice">`,
+		`<meta property="video:actor" name="video:actor" content="Bob">`,
+		`<meta property="video:duration" name="video:duration" content="5400">`,
+	}, "\n")
+	if !strings.Contains(result, expected) || strings.Contains(result, "product:") || strings.Contains(result, "video:series") {
+		t.Errorf("properties of og:type should be rendered with variables, but got %v", result)
+	}
+
+	db.Save(&QorSEOSetting{Name: "MoviePage", Setting: Setting{Title: "Movie", OpenGraphType: "product", OpenGraphObject: object, OpenGraphMetadata: []OpenGraphMetadata{{Property: "product:price:currency", Content: "EUR"}}}})
+	result = string(collection.Render(context, "MoviePage"))
+	if !strings.Contains(result, `product:price:amount" content="9.99"`) || !strings.Contains(result, `product:price:currency" content="EUR"`) || strings.Contains(result, "USD") || strings.Contains(result, "video:") {
+		t.Errorf("open graph metadata should replace properties of og:type, but got %v", result)
+	}
+
+	if properties := object.Properties("video.episode"); properties.Get("video:series") != "http://qor.test/series" {
+		t.Errorf("video:series should be rendered for episodes, but got %v", properties)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSettingsCSV(&buf, []ExportedSetting{{Name: "MoviePage", Setting: Setting{OpenGraphObject: object}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if settings, err := ReadSettingsCSV(&buf); err != nil || len(settings) != 1 || settings[0].Setting.OpenGraphObject != object {
+		t.Errorf("properties of open graph types should be exported, but got %v, %v", settings, err)
+	}
+}
+
+func TestValidateOpenGraphProperties(t *testing.T) {
+	testCases := []struct {
+		Type     string
+		Property string
+		Valid    bool
+	}{
+		{Type: "product", Property: "product:price:amount", Valid: true},
+		{Type: "product", Property: "video:actor", Valid: false},
+		{Type: "video.movie", Property: "video:series", Valid: false},
+		{Type: "video.episode", Property: "video:series", Valid: true},
+		{Type: "article", Property: "book:isbn", Valid: false},
+		{Type: "article", Property: "article:author", Valid: true},
+		{Type: "{{Type}}", Property: "book:isbn", Valid: true},
+		{Type: "", Property: "music:album", Valid: true},
+	}
+
+	for i, testCase := range testCases {
+		setting := Setting{OpenGraphType: testCase.Type, OpenGraphMetadata: []OpenGraphMetadata{{Property: testCase.Property, Content: "value"}}}
+		if err := setting.validateOpenGraphProperties(); (err == nil) != testCase.Valid {
+			t.Errorf("#%v: %v of %v should be valid: %v, but got %v", i+1, testCase.Property, testCase.Type, testCase.Valid, err)
+		}
+	}
+}
+
+func TestValidateOpenGraphPropertiesFromAdmin(t *testing.T) {
+	setupSeoCollection()
+	db.DropTableIfExists(&CustomizedProduct{})
+	db.AutoMigrate(&CustomizedProduct{})
+	res := Admin.AddResource(&CustomizedProduct{})
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	product := CustomizedProduct{Name: "Shoes"}
+	db.Create(&product)
+
+	update := func(property string) CustomizedProduct {
+		form := url.Values{
+			"_method":                                       {"PUT"},
+			"QorResource.Name":                              {"Shoes"},
+			"QorResource.SEO.CustomizedFields":              {"OpenGraphType", "OpenGraphMetadata", "OpenGraphObject"},
+			"QorResource.SEO.OpenGraphType":                 {"product"},
+			"QorResource.SEO.OpenGraphObject.Product.Brand": {"Qor"},
+			"QorResource.SEO.OpenGraphMetadata[0].Property": {property},
+			"QorResource.SEO.OpenGraphMetadata[0].Content":  {"value"},
+		}
+		if _, err := http.PostForm(fmt.Sprintf("%v/admin/%v/%v", server.URL, res.ToParam(), product.ID), form); err != nil {
+			t.Fatal(err)
+		}
+
+		var result CustomizedProduct
+		db.First(&result, product.ID)
+		return result
+	}
+
+	if result := update("video:actor"); result.SEO.OpenGraphType != "" {
+		t.Errorf("properties not allowed for og:type should not be saved, but got %#v", result.SEO)
+	}
+
+	if result := update("product:condition"); result.SEO.OpenGraphObject.Product.Brand != "Qor" || len(result.SEO.OpenGraphMetadata) != 1 {
+		t.Errorf("properties of og:type should be saved, but got %#v", result.SEO)
+	}
+}
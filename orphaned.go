@@ -0,0 +1,119 @@
+package seo
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OrphanedSettingsURL get url to delete or rename orphaned settings
+func (collection *Collection) OrphanedSettingsURL() string {
+	qorAdmin := collection.resource.GetAdmin()
+	return fmt.Sprintf("%v/%v/!seo_settings/orphaned", qorAdmin.GetRouter().Prefix, collection.resource.ToParam())
+}
+
+// OrphanedSettings return names of stored settings whose seo is not registered anymore, e.g: after its RegisterSEO call is removed.
+// Site-wide settings and settings of other collections added to the same admin are never orphaned
+func (collection *Collection) OrphanedSettings(db *gorm.DB) ([]string, error) {
+	var names []string
+	if err := db.Model(collection.SettingResource.NewStruct()).Where("is_global_seo = ?", false).Order("name").Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for _, name := range names {
+		if !collection.isRegisteredByAdmin(name) {
+			orphaned = append(orphaned, name)
+		}
+	}
+	return orphaned, nil
+}
+
+// DeleteOrphanedSetting delete an orphaned setting and its versions
+func (collection *Collection) DeleteOrphanedSetting(db *gorm.DB, name string) error {
+	if _, err := collection.findOrphanedSetting(db, name); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("name = ?", name).Delete(collection.SettingResource.NewStruct()).Error; err != nil {
+			return err
+		}
+		return tx.Where("name = ?", name).Delete(&QorSEOSettingVersion{}).Error
+	})
+}
+
+// RenameSetting migrate content of an orphaned setting to a registered seo, e.g: after the seo is registered with a new name.
+// Existing content of the registered seo is overwritten, versions of the orphaned setting are moved to it, then the orphaned setting is deleted
+func (collection *Collection) RenameSetting(db *gorm.DB, from, to string) error {
+	if !collection.isRegistered(to) {
+		return fmt.Errorf("seo: %v is not registered", to)
+	}
+
+	orphaned, err := collection.findOrphanedSetting(db, from)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := collection.SettingResource.NewStruct()
+		if err := tx.Where("name = ?", to).First(result).Error; gorm.IsRecordNotFoundError(err) {
+			result = collection.newSetting(to)
+		} else if err != nil {
+			return err
+		}
+
+		content := orphaned.GetSEOSetting()
+		content.Type = to
+		if err := setSEOSetting(result, content); err != nil {
+			return err
+		}
+
+		if draftInterface, ok := result.(QorSEOSettingDraftInterface); ok {
+			draftInterface.DiscardDraft()
+			if draft, hasDraft := draftOf(orphaned); hasDraft {
+				draft.Type = to
+				draftInterface.SetDraft(draft, orphaned.(QorSEOSettingDraftInterface).GetPublishAt())
+			}
+		}
+
+		if err := tx.Save(result).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&QorSEOSettingVersion{}).Where("name = ?", from).Update("name", to).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("name = ?", from).Delete(collection.SettingResource.NewStruct()).Error
+	})
+}
+
+// findOrphanedSetting find setting by name, return an error if it is not found or its seo is registered
+func (collection *Collection) findOrphanedSetting(db *gorm.DB, name string) (QorSEOSettingInterface, error) {
+	if name == collection.Name || collection.isRegisteredByAdmin(name) {
+		return nil, fmt.Errorf("seo: %v is registered", name)
+	}
+
+	result := collection.SettingResource.NewStruct()
+	if err := db.Where("name = ? AND is_global_seo = ?", name, false).First(result).Error; err != nil {
+		return nil, err
+	}
+	return result.(QorSEOSettingInterface), nil
+}
+
+func (collection *Collection) isRegistered(name string) bool {
+	for _, seo := range collection.registeredSEO {
+		if seo.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isRegisteredByAdmin check if the seo is registered by the collection, or other collections of its admin, which might share the settings table
+func (collection *Collection) isRegisteredByAdmin(name string) bool {
+	if collection.isRegistered(name) {
+		return true
+	}
+	return collection.resource != nil && collectionOf(collection.resource.GetAdmin(), name) != nil
+}
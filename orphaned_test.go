@@ -0,0 +1,117 @@
+package seo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+func TestOrphanedSettings(t *testing.T) {
+	setupSeoCollection()
+	db.Delete(&QorSEOSettingVersion{})
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "Category"})
+	db.Create(&QorSEOSetting{Name: "OldCategoryPage", Setting: Setting{Title: "Old {{Name}}", Type: "OldCategoryPage"}})
+	db.Create(&QorSEOSetting{Name: "RemovedPage", Setting: Setting{Title: "Removed"}})
+	db.Create(&QorSEOSettingVersion{Name: "OldCategoryPage", Setting: Setting{Title: "Old {{Name}}"}})
+	db.Create(&QorSEOSettingVersion{Name: "RemovedPage", Setting: Setting{Title: "Removed"}})
+
+	orphaned, err := collection.OrphanedSettings(db)
+	if err != nil || !reflect.DeepEqual(orphaned, []string{"OldCategoryPage", "RemovedPage"}) {
+		t.Errorf("should find orphaned settings, but got %v, %v", orphaned, err)
+	}
+
+	if err := collection.DeleteOrphanedSetting(db, "CategoryPage"); err == nil {
+		t.Errorf("should not delete settings of registered seos")
+	}
+
+	if err := collection.RenameSetting(db, "OldCategoryPage", "UnknownPage"); err == nil {
+		t.Errorf("should not rename to unregistered seos")
+	}
+
+	if err := collection.RenameSetting(db, "OldCategoryPage", "CategoryPage"); err != nil {
+		t.Fatalf("failed to rename setting, got %v", err)
+	}
+
+	var setting QorSEOSetting
+	if db.First(&setting, "name = ?", "CategoryPage"); setting.Setting.Title != "Old {{Name}}" || setting.Setting.Type != "CategoryPage" {
+		t.Errorf("content should be moved to the registered seo, but got %#v", setting.Setting)
+	}
+
+	if versions, _ := collection.Versions(&qor.Context{DB: db}, "CategoryPage"); len(versions) != 1 {
+		t.Errorf("versions should be moved to the registered seo, but got %#v", versions)
+	}
+
+	if err := collection.DeleteOrphanedSetting(db, "RemovedPage"); err != nil {
+		t.Fatalf("failed to delete orphaned setting, got %v", err)
+	}
+
+	var count int
+	if db.Unscoped().Model(&QorSEOSetting{}).Where("name IN (?)", []string{"OldCategoryPage", "RemovedPage"}).Count(&count); count != 0 {
+		t.Errorf("orphaned settings should be deleted, but got %v", count)
+	}
+
+	if db.Model(&QorSEOSettingVersion{}).Where("name = ?", "RemovedPage").Count(&count); count != 0 {
+		t.Errorf("versions of deleted setting should be deleted, but got %v", count)
+	}
+}
+
+func TestOrphanedSettingsFromAdmin(t *testing.T) {
+	setupSeoCollection()
+	db.Create(&QorSEOSetting{Name: "OldCategoryPage", Setting: Setting{Title: "Old"}})
+	db.Create(&QorSEOSetting{Name: "RemovedPage", Setting: Setting{Title: "Removed"}})
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	for _, form := range []url.Values{
+		{"_method": {"PUT"}, "QorSEOAction": {"rename"}, "name": {"OldCategoryPage"}, "to": {"CategoryPage"}},
+		{"_method": {"PUT"}, "QorSEOAction": {"delete"}, "name": {"RemovedPage"}},
+	} {
+		if _, err := http.PostForm(server.URL+collection.OrphanedSettingsURL(), form); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	db.Model(&QorSEOSetting{}).Order("name").Pluck("name", &names)
+	if !reflect.DeepEqual(names, []string{"CategoryPage"}) {
+		t.Errorf("orphaned settings should be renamed and deleted, but got %v", names)
+	}
+}
+
+func TestOrphanedSettingsOfCollectionsSharingTable(t *testing.T) {
+	setupSeoCollection()
+	blogCollection := New("Blog")
+	blogCollection.RegisterGlobalVaribles(&SeoGlobalSetting{})
+	blogCollection.RegisterSEO(&SEO{Name: "BlogPage"})
+	Admin.AddResource(blogCollection, &admin.Config{Name: "Blog SEO Setting"})
+
+	createCategoryPageSetting(Setting{Title: "Category"})
+	db.Create(&QorSEOSetting{Name: "BlogPage", Setting: Setting{Title: "Blog"}})
+	db.Create(&QorSEOSetting{Name: "RemovedPage", Setting: Setting{Title: "Removed"}})
+
+	for _, c := range []*Collection{collection, blogCollection} {
+		orphaned, err := c.OrphanedSettings(db)
+		if err != nil || !reflect.DeepEqual(orphaned, []string{"RemovedPage"}) {
+			t.Errorf("settings of collections of the same admin should not be orphaned, but got %v, %v", orphaned, err)
+		}
+	}
+
+	if err := collection.DeleteOrphanedSetting(db, "BlogPage"); err == nil {
+		t.Errorf("should not delete settings of other collections")
+	}
+
+	if err := collection.RenameSetting(db, "BlogPage", "CategoryPage"); err == nil {
+		t.Errorf("should not move content of settings of other collections")
+	}
+
+	var setting QorSEOSetting
+	if db.First(&setting, "name = ?", "BlogPage").RecordNotFound() || setting.Setting.Title != "Blog" {
+		t.Errorf("setting of other collection should be kept, but got %#v", setting.Setting)
+	}
+}
@@ -0,0 +1,350 @@
+package seo
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/admin"
+	"github.com/qor/validations"
+)
+
+// Redirect match types
+const (
+	RedirectMatchExact  = "exact"
+	RedirectMatchPrefix = "prefix"
+	RedirectMatchRegexp = "regexp"
+)
+
+// RedirectStatusCodes allowed status codes of redirects, http.StatusGone means the source is removed, so no target is needed
+var RedirectStatusCodes = []int{http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect, http.StatusGone}
+
+// QorSEORedirect a redirect rule from source path to target url.
+//
+// With prefix matching, rest of the path after source is appended to target. With regexp matching,
+// source is a regular expression matched against the whole path, and target could reference its submatches like $1
+type QorSEORedirect struct {
+	gorm.Model
+	Source        string `gorm:"index"`
+	Target        string
+	StatusCode    int
+	MatchType     string
+	PreserveQuery bool
+}
+
+// redirectsVersion increased every time redirects are changed in this process, redirectors will reload redirects when it is changed
+var redirectsVersion int64
+
+// BeforeSave set default status code and match type, validate the redirect and flatten its redirect chain
+func (redirect *QorSEORedirect) BeforeSave(tx *gorm.DB) error {
+	if redirect.StatusCode == 0 {
+		redirect.StatusCode = http.StatusMovedPermanently
+	}
+
+	if redirect.MatchType == "" {
+		redirect.MatchType = RedirectMatchExact
+	}
+
+	if err := redirect.Validate(); err != nil {
+		return err
+	}
+
+	return redirect.flattenChain(&redirectLookup{tx: tx, id: redirect.ID})
+}
+
+// AfterSave flatten redirects to the redirect, and notify redirectors to reload redirects
+func (redirect *QorSEORedirect) AfterSave(tx *gorm.DB) error {
+	atomic.AddInt64(&redirectsVersion, 1)
+	return redirect.flattenIncoming(tx)
+}
+
+// AfterDelete notify redirectors to reload redirects
+func (redirect *QorSEORedirect) AfterDelete() {
+	atomic.AddInt64(&redirectsVersion, 1)
+}
+
+// Validate validate the redirect's source, target, status code and match type
+func (redirect QorSEORedirect) Validate() error {
+	switch redirect.MatchType {
+	case RedirectMatchExact, RedirectMatchPrefix:
+		if !strings.HasPrefix(redirect.Source, "/") {
+			return validations.NewError(&redirect, "Source", "source should be a path starts with /")
+		}
+	case RedirectMatchRegexp:
+		if _, err := regexp.Compile(redirect.Source); err != nil || redirect.Source == "" {
+			return validations.NewError(&redirect, "Source", "source is not a valid regular expression")
+		}
+	default:
+		return validations.NewError(&redirect, "MatchType", "match type should be exact, prefix or regexp")
+	}
+
+	validStatus := false
+	for _, code := range RedirectStatusCodes {
+		validStatus = validStatus || code == redirect.StatusCode
+	}
+	if !validStatus {
+		return validations.NewError(&redirect, "StatusCode", "status code should be one of 301, 302, 307, 308 and 410")
+	}
+
+	if redirect.Target == "" && redirect.StatusCode != http.StatusGone {
+		return validations.NewError(&redirect, "Target", "target can't be blank")
+	}
+	return nil
+}
+
+func configureRedirectResource(res *admin.Resource) {
+	var statusCodes []string
+	for _, code := range RedirectStatusCodes {
+		statusCodes = append(statusCodes, strconv.Itoa(code))
+	}
+
+	res.Meta(&admin.Meta{Name: "StatusCode", Type: "select_one", Collection: statusCodes})
+	res.Meta(&admin.Meta{Name: "MatchType", Type: "select_one", Collection: []string{RedirectMatchExact, RedirectMatchPrefix, RedirectMatchRegexp}})
+	res.IndexAttrs("Source", "Target", "StatusCode", "MatchType", "PreserveQuery", "UpdatedAt")
+	res.EditAttrs("Source", "Target", "StatusCode", "MatchType", "PreserveQuery")
+	res.NewAttrs("Source", "Target", "StatusCode", "MatchType", "PreserveQuery")
+	res.SearchAttrs("Source", "Target")
+}
+
+// DefaultRedirectRefreshInterval default RefreshInterval of Redirector
+var DefaultRedirectRefreshInterval = time.Minute
+
+// Redirector an http middleware that applies redirects saved in database.
+// Redirects are compiled in memory, and reloaded when they are changed in this process or RefreshInterval has passed,
+// so redirects changed by other processes are applied after RefreshInterval
+type Redirector struct {
+	DB *gorm.DB
+	// RefreshInterval reload redirects periodically as they may be changed by other processes, default is DefaultRedirectRefreshInterval, negative means never
+	RefreshInterval time.Duration
+	// ErrorHandler handle errors of reloading redirects, previously loaded redirects are kept applying in that case, errors are logged if it is nil
+	ErrorHandler func(error)
+
+	mutex    sync.RWMutex
+	matcher  *redirectMatcher
+	version  int64
+	loadedAt time.Time
+
+	// reloadMutex only one request reloads redirects at a time
+	reloadMutex sync.Mutex
+}
+
+// NewRedirector initialize a redirector with redirects saved in db
+func NewRedirector(db *gorm.DB) *Redirector {
+	return &Redirector{DB: db}
+}
+
+// Reload load and compile redirects from database
+func (redirector *Redirector) Reload() error {
+	redirector.reloadMutex.Lock()
+	defer redirector.reloadMutex.Unlock()
+	_, err := redirector.reload()
+	return err
+}
+
+func (redirector *Redirector) reload() (*redirectMatcher, error) {
+	version := atomic.LoadInt64(&redirectsVersion)
+
+	var redirects []QorSEORedirect
+	if err := redirector.DB.Order("id").Find(&redirects).Error; err != nil {
+		return nil, err
+	}
+
+	matcher := newRedirectMatcher(redirects)
+	redirector.mutex.Lock()
+	redirector.matcher, redirector.version, redirector.loadedAt = matcher, version, time.Now()
+	redirector.mutex.Unlock()
+	return matcher, nil
+}
+
+// loadedMatcher return loaded redirects, reload them if they are stale
+func (redirector *Redirector) loadedMatcher() (*redirectMatcher, error) {
+	redirector.mutex.RLock()
+	matcher, version, loadedAt := redirector.matcher, redirector.version, redirector.loadedAt
+	redirector.mutex.RUnlock()
+
+	if matcher == nil {
+		// requests wait for the first load
+		redirector.reloadMutex.Lock()
+		defer redirector.reloadMutex.Unlock()
+
+		redirector.mutex.RLock()
+		matcher = redirector.matcher
+		redirector.mutex.RUnlock()
+		if matcher != nil {
+			return matcher, nil
+		}
+		return redirector.reload()
+	}
+
+	if !redirector.isStale(version, loadedAt) {
+		return matcher, nil
+	}
+
+	// stale redirects are reloaded by one request, other requests keep using them meanwhile or if the reload failed
+	if redirector.reloadMutex.TryLock() {
+		defer redirector.reloadMutex.Unlock()
+
+		redirector.mutex.RLock()
+		matcher, version, loadedAt = redirector.matcher, redirector.version, redirector.loadedAt
+		redirector.mutex.RUnlock()
+		if !redirector.isStale(version, loadedAt) {
+			return matcher, nil
+		}
+
+		if reloaded, err := redirector.reload(); err == nil {
+			matcher = reloaded
+		} else {
+			redirector.handleError(err)
+		}
+	}
+	return matcher, nil
+}
+
+func (redirector *Redirector) isStale(version int64, loadedAt time.Time) bool {
+	refreshInterval := redirector.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = DefaultRedirectRefreshInterval
+	}
+	return version != atomic.LoadInt64(&redirectsVersion) || (refreshInterval > 0 && time.Since(loadedAt) > refreshInterval)
+}
+
+func (redirector *Redirector) handleError(err error) {
+	if redirector.ErrorHandler != nil {
+		redirector.ErrorHandler(err)
+	} else {
+		log.Printf("seo: failed to reload redirects, got %v", err)
+	}
+}
+
+// Match find the redirect for an url, return the redirect and its target url.
+// Errors are only returned if redirects have never been loaded, errors of reloading them are passed to ErrorHandler
+func (redirector *Redirector) Match(u *url.URL) (*QorSEORedirect, string, error) {
+	matcher, err := redirector.loadedMatcher()
+	if err != nil {
+		return nil, "", err
+	}
+
+	redirect, target := matcher.match(u)
+	return redirect, target, nil
+}
+
+// Middleware redirect requests matched with redirects, or serve them with handler
+func (redirector *Redirector) Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		redirect, target, err := redirector.Match(req.URL)
+		if err != nil {
+			redirector.handleError(err)
+		}
+
+		if redirect == nil {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		if redirect.StatusCode == http.StatusGone {
+			http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+			return
+		}
+		http.Redirect(w, req, target, redirect.StatusCode)
+	})
+}
+
+// hasPathPrefix check path starts with prefix at a segment boundary, so /old matches /old and /old/page, but not /older
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// pathPrefixes return sources of exact and prefix redirects that could match path
+func pathPrefixes(path string) []string {
+	prefixes := []string{path}
+	for i, c := range path {
+		if c == '/' {
+			if i > 0 {
+				prefixes = append(prefixes, path[:i])
+			}
+			prefixes = append(prefixes, path[:i+1])
+		}
+	}
+	return prefixes
+}
+
+// redirectMatcher compiled redirects, exact redirects are matched first, then prefix redirects from the longest one,
+// then regexp redirects in the order they are created
+type redirectMatcher struct {
+	exact    map[string]*QorSEORedirect
+	prefixes []*QorSEORedirect
+	regexps  []compiledRegexpRedirect
+}
+
+type compiledRegexpRedirect struct {
+	redirect *QorSEORedirect
+	regexp   *regexp.Regexp
+}
+
+func newRedirectMatcher(redirects []QorSEORedirect) *redirectMatcher {
+	matcher := &redirectMatcher{exact: map[string]*QorSEORedirect{}}
+	for i := range redirects {
+		redirect := &redirects[i]
+		switch redirect.MatchType {
+		case RedirectMatchPrefix:
+			matcher.prefixes = append(matcher.prefixes, redirect)
+		case RedirectMatchRegexp:
+			if re, err := regexp.Compile(redirect.Source); err == nil {
+				matcher.regexps = append(matcher.regexps, compiledRegexpRedirect{redirect: redirect, regexp: re})
+			}
+		default:
+			if _, ok := matcher.exact[redirect.Source]; !ok {
+				matcher.exact[redirect.Source] = redirect
+			}
+		}
+	}
+
+	sort.SliceStable(matcher.prefixes, func(i, j int) bool {
+		return len(matcher.prefixes[i].Source) > len(matcher.prefixes[j].Source)
+	})
+	return matcher
+}
+
+func (matcher *redirectMatcher) match(u *url.URL) (*QorSEORedirect, string) {
+	path := u.Path
+	if redirect, ok := matcher.exact[path]; ok {
+		return redirect, redirectTarget(redirect, redirect.Target, u)
+	}
+
+	for _, redirect := range matcher.prefixes {
+		if hasPathPrefix(path, redirect.Source) {
+			return redirect, redirectTarget(redirect, redirect.Target+strings.TrimPrefix(path, redirect.Source), u)
+		}
+	}
+
+	for _, compiled := range matcher.regexps {
+		if match := compiled.regexp.FindStringSubmatchIndex(path); match != nil {
+			target := string(compiled.regexp.ExpandString(nil, compiled.redirect.Target, path, match))
+			return compiled.redirect, redirectTarget(compiled.redirect, target, u)
+		}
+	}
+	return nil, ""
+}
+
+// redirectTarget append query of request url to target if the redirect preserves query
+func redirectTarget(redirect *QorSEORedirect, target string, u *url.URL) string {
+	if !redirect.PreserveQuery || u.RawQuery == "" {
+		return target
+	}
+
+	if strings.Contains(target, "?") {
+		return target + "&" + u.RawQuery
+	}
+	return target + "?" + u.RawQuery
+}
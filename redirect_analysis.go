@@ -0,0 +1,165 @@
+package seo
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/validations"
+)
+
+// maxRedirectChain max redirects followed when flattening a redirect chain
+const maxRedirectChain = 20
+
+// BrokenRedirect a redirect whose target responds with 404 or 410
+type BrokenRedirect struct {
+	Redirect   QorSEORedirect
+	Target     string
+	StatusCode int
+}
+
+// redirectLookup find other redirects that could match a path, without loading all redirects
+type redirectLookup struct {
+	tx      *gorm.DB
+	id      uint
+	regexps []QorSEORedirect
+	loaded  bool
+}
+
+// matcher return a matcher of exact and prefix redirects whose source could match path, and all regexp redirects
+func (lookup *redirectLookup) matcher(path string) (*redirectMatcher, error) {
+	if !lookup.loaded {
+		if err := lookup.tx.Where("id <> ? AND match_type = ?", lookup.id, RedirectMatchRegexp).Order("id").Find(&lookup.regexps).Error; err != nil {
+			return nil, err
+		}
+		lookup.loaded = true
+	}
+
+	var redirects []QorSEORedirect
+	if err := lookup.tx.Where("id <> ? AND match_type <> ? AND source IN (?)", lookup.id, RedirectMatchRegexp, pathPrefixes(path)).Order("id").Find(&redirects).Error; err != nil {
+		return nil, err
+	}
+	return newRedirectMatcher(append(redirects, lookup.regexps...)), nil
+}
+
+// flattenChain point target of the redirect to the end of its redirect chain, so A -> B -> C is saved as A -> C.
+// It returns a validation error if the chain loops back to the redirect's source.
+// Only targets of exact redirects are flattened, targets of prefix and regexp redirects are only checked for loops
+func (redirect *QorSEORedirect) flattenChain(lookup *redirectLookup) error {
+	if redirect.StatusCode == http.StatusGone || strings.Contains(redirect.Target, "$") {
+		return nil
+	}
+
+	var (
+		self    = newRedirectMatcher([]QorSEORedirect{*redirect})
+		target  = redirect.Target
+		chain   = []string{redirect.Source, target}
+		visited = map[string]bool{}
+	)
+
+	for {
+		u, err := url.Parse(target)
+		if err != nil || u.Host != "" {
+			break
+		}
+
+		if matched, _ := self.match(u); matched != nil || visited[u.Path] {
+			return validations.NewError(redirect, "Target", fmt.Sprintf("redirect loop: %v", strings.Join(chain, " -> ")))
+		}
+		visited[u.Path] = true
+
+		matcher, err := lookup.matcher(u.Path)
+		if err != nil {
+			return err
+		}
+
+		next, nextTarget := matcher.match(u)
+		if next == nil || next.StatusCode == http.StatusGone {
+			break
+		}
+
+		if len(chain) > maxRedirectChain {
+			return validations.NewError(redirect, "Target", fmt.Sprintf("redirect chain is too long: %v", strings.Join(chain, " -> ")))
+		}
+
+		target = nextTarget
+		chain = append(chain, target)
+	}
+
+	if redirect.MatchType == RedirectMatchExact {
+		redirect.Target = target
+	}
+	return nil
+}
+
+// likeEscaper escape wildcards of LIKE patterns with ESCAPE '!'
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// flattenIncoming point exact redirects whose target is the redirect's source to the redirect's target, so saving B -> C updates A -> B to A -> C
+func (redirect *QorSEORedirect) flattenIncoming(tx *gorm.DB) error {
+	if redirect.StatusCode == http.StatusGone || strings.Contains(redirect.Target, "$") {
+		return nil
+	}
+
+	// only redirects whose target starts with the source could be matched, unless it is a regexp
+	query := tx.Where("id <> ? AND match_type = ?", redirect.ID, RedirectMatchExact)
+	if redirect.MatchType != RedirectMatchRegexp {
+		query = query.Where("target LIKE ? ESCAPE '!'", likeEscaper.Replace(redirect.Source)+"%")
+	}
+
+	var incoming []QorSEORedirect
+	if err := query.Find(&incoming).Error; err != nil {
+		return err
+	}
+
+	matcher := newRedirectMatcher([]QorSEORedirect{*redirect})
+	for _, r := range incoming {
+		u, err := url.Parse(r.Target)
+		if err != nil || u.Host != "" {
+			continue
+		}
+
+		if matched, target := matcher.match(u); matched != nil {
+			if err := tx.Model(&r).UpdateColumn("target", target).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckTargets request local targets of redirects with handler, and report redirects whose targets respond with 404 or 410.
+// Redirects returned by handler are followed, targets of regexp redirects that reference submatches are not checked
+func (redirector *Redirector) CheckTargets(handler http.Handler) (broken []BrokenRedirect, err error) {
+	var redirects []QorSEORedirect
+	if err = redirector.DB.Order("id").Find(&redirects).Error; err != nil {
+		return nil, err
+	}
+
+	crawler := Crawler{Handler: handler, Host: "localhost"}
+	for _, redirect := range redirects {
+		if redirect.StatusCode == http.StatusGone || strings.Contains(redirect.Target, "$") {
+			continue
+		}
+
+		link, ok := crawler.internalPath(redirect.Target, "")
+		if !ok {
+			continue
+		}
+
+		recorder := crawler.serve(link)
+		for hops := 0; hops < maxRedirectChain && recorder.Code >= 300 && recorder.Code < 400; hops++ {
+			if link, ok = crawler.internalPath(recorder.Header().Get("Location"), link); !ok {
+				break
+			}
+			recorder = crawler.serve(link)
+		}
+
+		if recorder.Code == http.StatusNotFound || recorder.Code == http.StatusGone {
+			broken = append(broken, BrokenRedirect{Redirect: redirect, Target: redirect.Target, StatusCode: recorder.Code})
+		}
+	}
+	return broken, nil
+}
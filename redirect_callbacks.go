@@ -0,0 +1,176 @@
+package seo
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SEOURLGetter models implement it to get a 301 redirect from old url to new url when the url is changed by an update
+type SEOURLGetter interface {
+	GetSEOURL() string
+}
+
+// URLTracker embed it in models that create redirects when their urls are changed, their urls are remembered when they are loaded or saved,
+// so updates compare urls without querying the old record
+type URLTracker struct {
+	seoURL       string
+	seoURLLoaded bool
+}
+
+func (tracker *URLTracker) setSEOURL(seoURL string) {
+	tracker.seoURL, tracker.seoURLLoaded = seoURL, true
+}
+
+func (tracker *URLTracker) loadedSEOURL() (string, bool) {
+	return tracker.seoURL, tracker.seoURLLoaded
+}
+
+type seoURLTracker interface {
+	setSEOURL(seoURL string)
+	loadedSEOURL() (string, bool)
+}
+
+// RegisterRedirectCallbacks register callbacks that create a 301 redirect when url of a record is changed by an update.
+// Models opt in by implementing SEOURLGetter, or with an url pattern in `seo` tag of the url field, e.g:
+//
+//	type Product struct {
+//		gorm.Model
+//		seo.URLTracker
+//		Code string `seo:"url:/products/{{Code}}"`
+//	}
+//
+// Old urls of models without URLTracker are queried before updates. Redirects are created after the update is committed
+func RegisterRedirectCallbacks(db *gorm.DB) {
+	callback := db.Callback()
+	if callback.Query().Get("seo:track_url") == nil {
+		callback.Query().After("gorm:after_query").Register("seo:track_url", trackURL)
+	}
+	if callback.Create().Get("seo:track_url") == nil {
+		callback.Create().After("gorm:commit_or_rollback_transaction").Register("seo:track_url", trackURL)
+	}
+	if callback.Update().Get("seo:record_old_url") == nil {
+		callback.Update().Before("gorm:update").Register("seo:record_old_url", recordOldURL)
+	}
+	if callback.Update().Get("seo:redirect_old_url") == nil {
+		callback.Update().After("gorm:commit_or_rollback_transaction").Register("seo:redirect_old_url", redirectOldURL)
+	}
+}
+
+const oldURLSettingKey = "seo:old_url"
+
+// trackURL remember urls of loaded or saved records that embed URLTracker
+func trackURL(scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(scope.Value))
+	switch value.Kind() {
+	case reflect.Struct:
+		trackURLOf(value)
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			trackURLOf(reflect.Indirect(value.Index(i)))
+		}
+	}
+}
+
+func trackURLOf(value reflect.Value) {
+	if !value.CanAddr() {
+		return
+	}
+
+	if tracker, ok := value.Addr().Interface().(seoURLTracker); ok {
+		if seoURL, ok := seoURLOf(tracker); ok {
+			tracker.setSEOURL(seoURL)
+		}
+	}
+}
+
+func recordOldURL(scope *gorm.Scope) {
+	if scope.HasError() || scope.PrimaryKeyZero() || reflect.Indirect(reflect.ValueOf(scope.Value)).Kind() != reflect.Struct {
+		return
+	}
+
+	if _, ok := seoURLOf(scope.Value); !ok {
+		return
+	}
+
+	if tracker, ok := scope.Value.(seoURLTracker); ok {
+		if oldURL, loaded := tracker.loadedSEOURL(); loaded {
+			scope.InstanceSet(oldURLSettingKey, oldURL)
+			return
+		}
+	}
+
+	old := reflect.New(scope.GetModelStruct().ModelType).Interface()
+	if scope.NewDB().Unscoped().Where(fmt.Sprintf("%v = ?", scope.Quote(scope.PrimaryKey())), scope.PrimaryKeyValue()).First(old).Error == nil {
+		if oldURL, ok := seoURLOf(old); ok {
+			scope.InstanceSet(oldURLSettingKey, oldURL)
+		}
+	}
+}
+
+// redirectOldURL create the redirect after the update is committed, so it doesn't hold locks of the update
+func redirectOldURL(scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+
+	value, ok := scope.InstanceGet(oldURLSettingKey)
+	if !ok {
+		return
+	}
+	trackURL(scope)
+
+	oldURL, newURL := value.(string), ""
+	if newURL, ok = seoURLOf(scope.Value); !ok || newURL == "" || oldURL == "" || oldURL == newURL {
+		return
+	}
+
+	u, err := url.Parse(oldURL)
+	if err != nil || u.Path == "" {
+		return
+	}
+
+	db := scope.NewDB()
+	// the new url is live now, redirects from it would hide the record
+	if newU, err := url.Parse(newURL); err == nil && newU.Path != "" {
+		if scope.Err(db.Where("source = ? AND match_type = ?", newU.Path, RedirectMatchExact).Delete(&QorSEORedirect{}).Error) != nil {
+			return
+		}
+	}
+
+	redirect := QorSEORedirect{}
+	db.Where("source = ? AND match_type = ?", u.Path, RedirectMatchExact).First(&redirect)
+	redirect.Source, redirect.Target, redirect.StatusCode, redirect.MatchType = u.Path, newURL, http.StatusMovedPermanently, RedirectMatchExact
+	scope.Err(db.Save(&redirect).Error)
+}
+
+// seoURLOf return url of a record if it implements SEOURLGetter or has a field with url pattern in `seo` tag
+func seoURLOf(record interface{}) (string, bool) {
+	if getter, ok := record.(SEOURLGetter); ok {
+		return getter.GetSEOURL(), true
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(record))
+	if value.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		if pattern, ok := parseSEOTag(value.Type().Field(i).Tag.Get("seo"))["url"]; ok {
+			return variableRegexp.ReplaceAllStringFunc(pattern, func(match string) string {
+				if field := value.FieldByName(variableRegexp.FindStringSubmatch(match)[1]); field.IsValid() {
+					return fmt.Sprint(field.Interface())
+				}
+				return ""
+			}), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,97 @@
+package seo
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+type RedirectProduct struct {
+	gorm.Model
+	URLTracker
+	Name string
+	Code string `seo:"url:/products/{{Code}}"`
+}
+
+type RedirectCategory struct {
+	gorm.Model
+	Slug string
+}
+
+func (category RedirectCategory) GetSEOURL() string {
+	return "/categories/" + category.Slug
+}
+
+func TestRedirectCallbacks(t *testing.T) {
+	setupRedirects()
+	db.DropTableIfExists(&RedirectProduct{}, &RedirectCategory{})
+	db.AutoMigrate(&RedirectProduct{}, &RedirectCategory{})
+	RegisterRedirectCallbacks(db)
+
+	product := RedirectProduct{Name: "Shoes", Code: "shoes"}
+	db.Create(&product)
+
+	product.Name = "Running Shoes"
+	db.Save(&product)
+
+	var count int
+	if db.Model(&QorSEORedirect{}).Count(&count); count != 0 {
+		t.Errorf("should not create redirect when url is not changed, but got %v", count)
+	}
+
+	product.Code = "running-shoes"
+	db.Save(&product)
+	db.Model(&product).Update("code", "runners")
+
+	var redirects []QorSEORedirect
+	db.Order("source").Find(&redirects)
+	if len(redirects) != 2 ||
+		redirects[0].Source != "/products/running-shoes" || redirects[0].Target != "/products/runners" ||
+		redirects[1].Source != "/products/shoes" || redirects[1].Target != "/products/runners" || redirects[1].StatusCode != 301 {
+		t.Errorf("should create redirects from old urls, and flatten them to the latest url, but got %#v", redirects)
+	}
+
+	db.Model(&product).Update("code", "shoes")
+	redirects = nil
+	db.Order("source").Find(&redirects)
+	if len(redirects) != 2 || redirects[0].Source != "/products/runners" || redirects[1].Source != "/products/running-shoes" || redirects[1].Target != "/products/shoes" {
+		t.Errorf("redirect from the url should be removed when it is used again, but got %#v", redirects)
+	}
+
+	var loaded RedirectProduct
+	db.First(&loaded, product.ID)
+	queries := 0
+	db.Callback().Query().After("gorm:query").Register("seo_test:products", func(scope *gorm.Scope) {
+		if scope.TableName() == "redirect_products" {
+			queries++
+		}
+	})
+	loaded.Code = "sneakers"
+	db.Save(&loaded)
+	db.Callback().Query().Remove("seo_test:products")
+
+	var sneakers QorSEORedirect
+	if db.First(&sneakers, "source = ?", "/products/shoes"); queries != 0 || sneakers.Target != "/products/sneakers" {
+		t.Errorf("urls of loaded records should be compared without querying old records, but got %v queries, %#v", queries, sneakers)
+	}
+
+	category := RedirectCategory{Slug: "hats"}
+	db.Create(&category)
+	db.Model(&category).Update("slug", "caps")
+
+	var redirect QorSEORedirect
+	if db.First(&redirect, "source = ?", "/categories/hats"); redirect.Target != "/categories/caps" {
+		t.Errorf("should create redirect for models implement SEOURLGetter, but got %#v", redirect)
+	}
+}
+
+func TestParseSEOTag(t *testing.T) {
+	settings := parseSEOTag("type:CategoryPage; url:/categories/{{Code}};noindex")
+	if settings["type"] != "CategoryPage" || settings["url"] != "/categories/{{Code}}" {
+		t.Errorf("failed to parse seo tag, got %#v", settings)
+	}
+
+	if _, ok := settings["noindex"]; !ok {
+		t.Errorf("tag without value should be parsed, got %#v", settings)
+	}
+}
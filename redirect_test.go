@@ -0,0 +1,286 @@
+package seo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/admin"
+)
+
+func setupRedirects(redirects ...QorSEORedirect) {
+	db.DropTableIfExists(&QorSEORedirect{})
+	db.AutoMigrate(&QorSEORedirect{})
+	for _, redirect := range redirects {
+		if err := db.Create(&redirect).Error; err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestRedirector(t *testing.T) {
+	setupRedirects(
+		QorSEORedirect{Source: "/old", Target: "/new"},
+		QorSEORedirect{Source: "/temporary", Target: "/promotion", StatusCode: http.StatusFound, PreserveQuery: true},
+		QorSEORedirect{Source: "/products/", Target: "/shop/", MatchType: RedirectMatchPrefix, StatusCode: http.StatusPermanentRedirect},
+		QorSEORedirect{Source: "/products/legacy/", Target: "/archive/", MatchType: RedirectMatchPrefix},
+		QorSEORedirect{Source: `^/categories/(\d+)$`, Target: "/c/$1?from=category", MatchType: RedirectMatchRegexp, PreserveQuery: true},
+		QorSEORedirect{Source: "/removed", StatusCode: http.StatusGone},
+	)
+
+	redirector := NewRedirector(db)
+	handler := redirector.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	cases := []struct {
+		URL      string
+		Status   int
+		Location string
+	}{
+		{URL: "/old", Status: http.StatusMovedPermanently, Location: "/new"},
+		{URL: "/old?page=2", Status: http.StatusMovedPermanently, Location: "/new"},
+		{URL: "/temporary?utm_source=mail", Status: http.StatusFound, Location: "/promotion?utm_source=mail"},
+		{URL: "/products/shoes", Status: http.StatusPermanentRedirect, Location: "/shop/shoes"},
+		{URL: "/products/legacy/shoes", Status: http.StatusMovedPermanently, Location: "/archive/shoes"},
+		{URL: "/categories/12?page=2", Status: http.StatusMovedPermanently, Location: "/c/12?from=category&page=2"},
+		{URL: "/categories/shoes", Status: http.StatusOK},
+		{URL: "/removed", Status: http.StatusGone},
+		{URL: "/home", Status: http.StatusOK},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", c.URL, nil))
+		if w.Code != c.Status || w.Header().Get("Location") != c.Location {
+			t.Errorf("%v: expect %v %v, but got %v %v", c.URL, c.Status, c.Location, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	db.Create(&QorSEORedirect{Source: "/home", Target: "/"})
+	if redirect, target, _ := redirector.Match(&url.URL{Path: "/home"}); redirect == nil || target != "/" {
+		t.Errorf("redirects should be reloaded after changed, but got %v", target)
+	}
+}
+
+func TestRedirectValidation(t *testing.T) {
+	setupRedirects()
+
+	invalid := []QorSEORedirect{
+		{Source: "old", Target: "/new"},
+		{Source: "/old"},
+		{Source: "/old", Target: "/new", StatusCode: http.StatusOK},
+		{Source: "(", Target: "/new", MatchType: RedirectMatchRegexp},
+		{Source: "/old", Target: "/new", MatchType: "glob"},
+	}
+
+	for _, redirect := range invalid {
+		if err := db.Create(&redirect).Error; err == nil {
+			t.Errorf("redirect %#v should be invalid", redirect)
+		}
+	}
+
+	redirect := QorSEORedirect{Source: "/old", Target: "/new"}
+	if err := db.Create(&redirect).Error; err != nil || redirect.StatusCode != http.StatusMovedPermanently || redirect.MatchType != RedirectMatchExact {
+		t.Errorf("redirect should be saved with default status code and match type, but got %v %#v", err, redirect)
+	}
+}
+
+func TestRedirectAdminResource(t *testing.T) {
+	setupSeoCollection()
+	setupRedirects()
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	form := url.Values{
+		"QorResource.Source":     {"/old"},
+		"QorResource.Target":     {"/new"},
+		"QorResource.StatusCode": {"308"},
+		"QorResource.MatchType":  {RedirectMatchPrefix},
+	}
+	if _, err := http.PostForm(server.URL+"/admin/"+collection.RedirectResource.ToParam(), form); err != nil {
+		t.Fatal(err)
+	}
+
+	var redirect QorSEORedirect
+	if db.First(&redirect).Error != nil || redirect.Source != "/old" || redirect.StatusCode != http.StatusPermanentRedirect || redirect.MatchType != RedirectMatchPrefix {
+		t.Errorf("redirect should be created from admin, but got %#v", redirect)
+	}
+
+	blogCollection := New("Blog")
+	blogCollection.RegisterGlobalVaribles(&SeoGlobalSetting{})
+	Admin.AddResource(blogCollection, &admin.Config{Name: "Blog SEO Setting"})
+	if blogCollection.RedirectResource != collection.RedirectResource || blogCollection.NotFoundResource != collection.NotFoundResource {
+		t.Errorf("redirect and 404 log resources should be shared by collections of the same admin")
+	}
+
+	var actions int
+	for _, action := range collection.NotFoundResource.GetActions() {
+		if action.Name == "Create Redirect" {
+			actions++
+		}
+	}
+	if actions != 1 {
+		t.Errorf("shared resources should be configured once, but got %v actions", actions)
+	}
+}
+
+func TestRedirectChains(t *testing.T) {
+	setupRedirects(QorSEORedirect{Source: "/a", Target: "/b"})
+
+	if err := db.Create(&QorSEORedirect{Source: "/b", Target: "/c?from=b"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var redirect QorSEORedirect
+	if db.First(&redirect, "source = ?", "/a"); redirect.Target != "/c?from=b" {
+		t.Errorf("redirect to a redirect's source should be flattened, but got %v", redirect.Target)
+	}
+
+	redirect = QorSEORedirect{Source: "/d", Target: "/a"}
+	if err := db.Create(&redirect).Error; err != nil || redirect.Target != "/c?from=b" {
+		t.Errorf("redirect's target should be flattened to the end of its chain, but got %v %v", err, redirect.Target)
+	}
+
+	loops := []QorSEORedirect{
+		{Source: "/c", Target: "/a"},
+		{Source: "/x", Target: "/x"},
+		{Source: "/shop/", Target: "/shop/all/", MatchType: RedirectMatchPrefix},
+		{Source: "/sale", Target: "/sale/all", MatchType: RedirectMatchPrefix},
+	}
+	for _, loop := range loops {
+		if err := db.Create(&loop).Error; err == nil || !strings.Contains(err.Error(), "redirect loop") {
+			t.Errorf("redirect loop %v -> %v should be rejected, but got %v", loop.Source, loop.Target, err)
+		}
+	}
+
+	prefix := QorSEORedirect{Source: "/old", Target: "/older", MatchType: RedirectMatchPrefix}
+	if err := db.Create(&prefix).Error; err != nil {
+		t.Errorf("prefix redirects should only match at segment boundaries, but got %v", err)
+	}
+
+	matcher := newRedirectMatcher([]QorSEORedirect{prefix})
+	for path, expected := range map[string]string{"/old": "/older", "/old/shoes": "/older/shoes", "/older": "", "/oldies/shoes": ""} {
+		if _, target := matcher.match(&url.URL{Path: path}); target != expected {
+			t.Errorf("%v should be redirected to %q, but got %q", path, expected, target)
+		}
+	}
+}
+
+func TestRedirectChainsWithoutLoadingAllRedirects(t *testing.T) {
+	setupRedirects()
+	for i := 0; i < 50; i++ {
+		db.Create(&QorSEORedirect{Source: fmt.Sprintf("/unrelated/%v", i), Target: "/home"})
+	}
+	db.Create(&QorSEORedirect{Source: "/a", Target: "/b"})
+
+	var loaded int64
+	db.Callback().Query().After("gorm:query").Register("seo_test:redirects", func(scope *gorm.Scope) {
+		if scope.TableName() == "qor_seo_redirects" {
+			loaded += scope.DB().RowsAffected
+		}
+	})
+	defer db.Callback().Query().Remove("seo_test:redirects")
+
+	redirect := QorSEORedirect{Source: "/b", Target: "/c"}
+	if err := db.Create(&redirect).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded != 1 {
+		t.Errorf("only related redirects should be loaded when saving a redirect, but got %v", loaded)
+	}
+
+	var a QorSEORedirect
+	if db.First(&a, "source = ?", "/a"); a.Target != "/c" {
+		t.Errorf("redirect to a redirect's source should be flattened, but got %v", a.Target)
+	}
+}
+
+func TestCheckRedirectTargets(t *testing.T) {
+	setupRedirects(
+		QorSEORedirect{Source: "/old", Target: "/new"},
+		QorSEORedirect{Source: "/legacy", Target: "/moved"},
+		QorSEORedirect{Source: "/broken", Target: "/missing"},
+		QorSEORedirect{Source: "/external", Target: "https://example.com/missing"},
+		QorSEORedirect{Source: "/removed", StatusCode: http.StatusGone},
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/new", func(w http.ResponseWriter, req *http.Request) { w.Write([]byte("new")) })
+	mux.Handle("/moved", http.RedirectHandler("/new", http.StatusMovedPermanently))
+
+	broken, err := NewRedirector(db).CheckTargets(mux)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(broken) != 1 || broken[0].Redirect.Source != "/broken" || broken[0].StatusCode != http.StatusNotFound {
+		t.Errorf("should report redirects to missing pages, but got %#v", broken)
+	}
+}
+
+func TestRedirectorReload(t *testing.T) {
+	setupRedirects(QorSEORedirect{Source: "/old", Target: "/new"})
+
+	var (
+		mutex   sync.Mutex
+		queries int
+		fail    bool
+		errs    []error
+	)
+	redirector := NewRedirector(db)
+	redirector.ErrorHandler = func(err error) {
+		mutex.Lock()
+		errs = append(errs, err)
+		mutex.Unlock()
+	}
+
+	db.Callback().Query().After("gorm:query").Register("seo_test:redirects", func(scope *gorm.Scope) {
+		if scope.TableName() == "qor_seo_redirects" {
+			mutex.Lock()
+			if queries++; fail {
+				scope.Err(errors.New("failed"))
+			}
+			mutex.Unlock()
+		}
+	})
+	defer db.Callback().Query().Remove("seo_test:redirects")
+
+	match := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if redirect, target, err := redirector.Match(&url.URL{Path: "/old"}); err != nil || redirect == nil || target != "/new" {
+					t.Errorf("redirects should be matched, but got %v, %v", target, err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	match()
+	atomic.AddInt64(&redirectsVersion, 1)
+	match()
+	if queries != 2 {
+		t.Errorf("redirects should be loaded once at a time, but got %v queries", queries)
+	}
+
+	mutex.Lock()
+	fail = true
+	mutex.Unlock()
+	atomic.AddInt64(&redirectsVersion, 1)
+	match()
+	if len(errs) == 0 {
+		t.Errorf("errors of reloading redirects should be passed to ErrorHandler")
+	}
+}
@@ -3,11 +3,14 @@ package seo
 import (
 	"fmt"
 	"html/template"
+	"image"
 	"net/url"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 
+	"github.com/jinzhu/gorm"
 	"github.com/qor/admin"
 	"github.com/qor/media"
 	"github.com/qor/qor"
@@ -25,13 +28,22 @@ func New(name string) *Collection {
 
 // Collection will hold registered seo configures and global setting definition and other configures
 type Collection struct {
-	Name            string
-	SettingResource *admin.Resource
+	Name             string
+	SettingResource  *admin.Resource
+	RedirectResource *admin.Resource
+	NotFoundResource *admin.Resource
+	// ErrorHandler handle errors of GetSEOSetting and Render, e.g: report to error tracking services. Errors are logged if it is nil
+	ErrorHandler func(context *qor.Context, err error)
+	// ReportNotFound pass *SettingNotFoundError of pages without saved setting to ErrorHandler too
+	ReportNotFound bool
+	// VerificationSEO name of the seo site verification tags are rendered with, e.g: home page, they are rendered on every page if it is blank
+	VerificationSEO string
 
 	registeredSEO  []*SEO
 	resource       *admin.Resource
 	globalResource *admin.Resource
 	globalSetting  interface{}
+	auditRunner    *auditRunner
 }
 
 // SEO represents a seo object for a page
@@ -40,13 +52,29 @@ type SEO struct {
 	Varibles   []string
 	OpenGraph  *OpenGraphConfig
 	Context    func(...interface{}) map[string]string
+	Sitemap    func(*qor.Context) ([]SitemapURL, error)
 	collection *Collection
+
+	// Parent return parent of a record and the seo name it is rendered with, e.g: category of a product,
+	// fields not customized by the record are inherited from customized settings of its parents, then the page setting
+	Parent func(record interface{}) (name string, parent interface{})
+	// Locales return locale of the page and locales it is available in, rendered as og:locale and og:locale:alternate,
+	// field of site-wide setting tagged with `seo:"tag:og:locale"` is used if the page's locale is blank
+	Locales func(context *qor.Context, objects ...interface{}) (current string, available []string)
+	// AppLinks deep links of the page in mobile apps, rendered as App Links tags with app ids of site-wide setting, see AppLinks
+	AppLinks *AppLinkConfig
 }
 
 // OpenGraphConfig open graph config
 type OpenGraphConfig struct {
 	ImageResource *admin.Resource
 	Size          *media.Size
+	// Generator generate og:image with title of the page for settings without image url, image selected from media library is used as background, then TemplateImage
+	Generator     *OpenGraphImageGenerator
+	TemplateImage image.Image
+
+	// mediaOptions options of images selected from media library by media id, see OpenGraphMediaCacheTTL
+	mediaOptions sync.Map
 }
 
 // RegisterGlobalVaribles register global setting struct and will represents as 'Site-wide Settings' part in admin
@@ -63,59 +91,67 @@ func (collection *Collection) RegisterSEO(seo *SEO) {
 	collection.registeredSEO = append(collection.registeredSEO, seo)
 }
 
-const currentSeoFieldIndicator = "CurrentSeoField"
-
-// GetSEOSetting return SEO title, keywords and description and open graph settings
+// GetSEOSetting return SEO title, keywords and description and open graph settings, errors are passed to ErrorHandler
 func (collection Collection) GetSEOSetting(context *qor.Context, name string, objects ...interface{}) Setting {
+	seoSetting, err := collection.GetSEOSettingE(context, name, objects...)
+	if err != nil {
+		collection.handleError(context, err)
+	}
+	return seoSetting
+}
+
+// GetSEOSettingE return SEO title, keywords and description and open graph settings, or an error if failed to load them.
+// A *SettingNotFoundError is returned with setting filled by global variables if the seo has no saved setting
+func (collection Collection) GetSEOSettingE(context *qor.Context, name string, objects ...interface{}) (Setting, error) {
+	seoSetting, _, err := collection.getSEOSetting(context, nil, name, objects...)
+	return seoSetting, err
+}
+
+// settingValues values of site-wide setting, and variables filled into the setting, which are site-wide values and values of SEO.Context
+type settingValues struct {
+	SiteWide  map[string]string
+	Variables map[string]string
+}
+
+// getSEOSetting return SEO setting with values of site-wide setting and variables, loaded settings are kept in cache if it is not nil
+func (collection Collection) getSEOSetting(context *qor.Context, cache *settingCache, name string, objects ...interface{}) (Setting, settingValues, error) {
 	var (
-		seoSetting          Setting
-		db                  = context.GetDB()
-		seo                 = collection.GetSEO(name)
-		hasMultiSeoField    = false
-		currentSeoFieldName = ""
+		record     interface{}
+		seoSetting Setting
+		seo        = collection.GetSEO(name)
 	)
 
-	// If passed objects has customzied SEO Setting field
+	// If passed objects has customzied SEO Setting field, the field tagged with the seo name is used for records have multiple Setting fields
 	for _, obj := range objects {
-		if value := reflect.Indirect(reflect.ValueOf(obj)); value.IsValid() && value.Kind() == reflect.Struct {
-			for i := 0; i < value.NumField(); i++ {
-				// To support multiple SEO field in one struct, you must add a field with name of currentSeoFieldIndicator's value
-				// NOTE: this field must be set before the seo field.
-				// When rendering the page, set record.CurrentSeoField = "current seo name".
-				// If the value of CurrentSeoField is empty, use the first seo field as default.
-				if value.Type().Field(i).Name == currentSeoFieldIndicator {
-					hasMultiSeoField = true
-					currentSeoFieldName = value.Field(i).String()
-				}
-
-				if value.Field(i).Type() == reflect.TypeOf(Setting{}) {
-					if hasMultiSeoField && currentSeoFieldName != "" {
-						if value.Type().Field(i).Name == currentSeoFieldName {
-							seoSetting = value.Field(i).Interface().(Setting)
-							break
-						}
-					} else {
-						seoSetting = value.Field(i).Interface().(Setting)
-						break
-					}
-				}
-			}
+		if setting, ok := settingFieldFor(obj, name); ok {
+			record, seoSetting = obj, setting
 		}
 	}
 
-	if !seoSetting.EnabledCustomize {
-		globalSeoSetting := collection.SettingResource.NewStruct().(QorSEOSettingInterface)
-		if !db.Where("name = ?", name).First(globalSeoSetting).RecordNotFound() {
-			seoSetting = globalSeoSetting.GetSEOSetting()
-		}
+	if seoSetting.decodeErr != nil {
+		return Setting{}, settingValues{}, seoSetting.decodeErr
 	}
 
-	siteWideSetting := collection.SettingResource.NewStruct()
-	db.Where("is_global_seo = ? AND name = ?", true, collection.Name).First(siteWideSetting)
-	tagValues := siteWideSetting.(QorSEOSettingInterface).GetGlobalSetting()
+	// parents are resolved from the first object if no object has Setting field
+	if record == nil && len(objects) > 0 {
+		record = objects[0]
+	}
+
+	// fields are customized one by one, others are inherited from parents' customized settings, then page setting
+	levels, notFoundErr := collection.inheritanceChain(context, cache, seo, record, seoSetting)
+	if _, ok := notFoundErr.(*SettingNotFoundError); notFoundErr != nil && !ok {
+		return Setting{}, settingValues{}, notFoundErr
+	}
+	seoSetting = resolveChain(levels).Setting
 
-	if tagValues == nil {
-		tagValues = map[string]string{}
+	siteWideValues, err := collection.loadSiteWideValues(context, cache)
+	if err != nil {
+		return Setting{}, settingValues{}, err
+	}
+
+	tagValues := map[string]string{}
+	for key, value := range siteWideValues {
+		tagValues[key] = value
 	}
 
 	if seo.Context != nil {
@@ -124,13 +160,72 @@ func (collection Collection) GetSEOSetting(context *qor.Context, name string, ob
 		}
 	}
 
-	return replaceTags(seoSetting, seo.Varibles, tagValues)
+	return replaceTags(seoSetting, seo.Varibles, tagValues), settingValues{SiteWide: siteWideValues, Variables: tagValues}, notFoundErr
 }
 
-// Render render SEO Setting
+// loadSiteWideValues load values of site-wide setting with its draft in effect, loaded values are kept in cache if it is not nil
+func (collection Collection) loadSiteWideValues(context *qor.Context, cache *settingCache) (map[string]string, error) {
+	if cache != nil && cache.siteWideLoaded {
+		return cache.siteWide, nil
+	}
+
+	siteWideSetting := collection.SettingResource.NewStruct()
+	if err := context.GetDB().Where("is_global_seo = ? AND name = ?", true, collection.Name).First(siteWideSetting).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, fmt.Errorf("seo: failed to load site-wide setting: %w", err)
+	}
+	if err := siteWideSetting.(QorSEOSettingInterface).GetSEOSetting().decodeErr; err != nil {
+		return nil, err
+	}
+	siteWideValues := siteWideSetting.(QorSEOSettingInterface).GetGlobalSetting()
+	if draft, ok := draftInEffect(context, siteWideSetting); ok {
+		siteWideValues = draft.GlobalSetting
+	}
+
+	if cache != nil {
+		cache.siteWide, cache.siteWideLoaded = siteWideValues, true
+	}
+	return siteWideValues, nil
+}
+
+// Render render SEO Setting, errors are passed to ErrorHandler
 func (collection Collection) Render(context *qor.Context, name string, objects ...interface{}) template.HTML {
-	seoSetting := collection.GetSEOSetting(context, name, objects...)
-	return seoSetting.FormattedHTML(context)
+	result, err := collection.RenderE(context, name, objects...)
+	if err != nil {
+		collection.handleError(context, err)
+	}
+	return result
+}
+
+// RenderE render SEO Setting, or return an error if failed to load or render it.
+// Tags filled by global variables are still rendered with a *SettingNotFoundError if the seo has no saved setting,
+// tags except og:image are still rendered with the error if failed to load or generate the open graph image
+func (collection Collection) RenderE(context *qor.Context, name string, objects ...interface{}) (template.HTML, error) {
+	seoSetting, values, err := collection.getSEOSetting(context, nil, name, objects...)
+	if _, ok := err.(*SettingNotFoundError); err != nil && !ok {
+		return "", err
+	}
+
+	// og:image is rendered with the variant cropped with OpenGraph.Size, and its width, height, type and alt,
+	// other tags are still rendered if failed to load the image
+	seo := collection.GetSEO(name)
+	imageMetadata, imageErr := seo.openGraphImageMetadata(context, seoSetting)
+	if imageErr != nil {
+		imageMetadata, err = []OpenGraphMetadata{{Property: "og:image"}}, imageErr
+	}
+
+	// properties of site-wide setting and page's locales, they are replaced by OpenGraphMetadata of the setting like other default properties
+	siteMetadata := collection.siteWideMetadata(context, seo, values.SiteWide, objects...)
+
+	// deep links of the page and app ids of site-wide setting, and verification tokens of webmaster tools
+	appLinksMetadata := seo.appLinksMetadata(values)
+	verificationMetadata := collection.verificationMetadata(seo, values.SiteWide)
+
+	defaults := append(append(imageMetadata, siteMetadata...), appLinksMetadata...)
+	result, renderErr := seoSetting.formattedHTML(context, append(defaults, verificationMetadata...)...)
+	if renderErr != nil {
+		return "", renderErr
+	}
+	return result, err
 }
 
 // GetSEO get a Seo by name
@@ -150,11 +245,20 @@ func (collection *Collection) SEOSettingURL(name string) string {
 	return fmt.Sprintf("%v/%v/!seo_setting?name=%v", qorAdmin.GetRouter().Prefix, collection.resource.ToParam(), url.QueryEscape(name))
 }
 
+// SEOSettingVersionsURL get setting's version history url by name
+func (collection *Collection) SEOSettingVersionsURL(name string) string {
+	qorAdmin := collection.resource.GetAdmin()
+	return fmt.Sprintf("%v/%v/!seo_setting/versions?name=%v", qorAdmin.GetRouter().Prefix, collection.resource.ToParam(), url.QueryEscape(name))
+}
+
 // ConfigureQorResource configure seoCollection for qor admin
 func (collection *Collection) ConfigureQorResource(res resource.Resourcer) {
 	if res, ok := res.(*admin.Resource); ok {
 		Admin := res.GetAdmin()
 		collection.resource = res
+		if collection.auditRunner == nil {
+			collection.auditRunner = &auditRunner{}
+		}
 		if collection.SettingResource == nil {
 			collection.SettingResource = Admin.AddResource(&QorSEOSetting{}, &admin.Config{Invisible: true})
 		}
@@ -165,7 +269,12 @@ func (collection *Collection) ConfigureQorResource(res resource.Resourcer) {
 			nameMeta.Type = "hidden"
 		}
 
+		// redirects and 404 logs are shared by all collections of the admin
+		collection.RedirectResource = sharedResource(Admin, collection.RedirectResource, &QorSEORedirect{}, &admin.Config{Name: "SEO Redirect", Menu: res.Config.Menu}, configureRedirectResource)
+		collection.NotFoundResource = sharedResource(Admin, collection.NotFoundResource, &QorSEONotFound{}, &admin.Config{Name: "SEO 404 Log", Menu: res.Config.Menu}, configureNotFoundResource)
+
 		globalSettingRes := Admin.AddResource(collection.globalSetting, &admin.Config{Invisible: true})
+		configureSiteWideSections(globalSettingRes)
 		collection.globalResource = globalSettingRes
 
 		res.Config.Singleton = true
@@ -176,20 +285,70 @@ func (collection *Collection) ConfigureQorResource(res resource.Resourcer) {
 		router.Get(res.ToParam(), controller.Index)
 		router.Put(fmt.Sprintf("%v/!seo_setting", res.ToParam()), controller.Update)
 		router.Get(fmt.Sprintf("%v/!seo_setting", res.ToParam()), controller.InlineEdit)
+		router.Get(fmt.Sprintf("%v/!seo_setting/versions", res.ToParam()), controller.Versions)
+		router.Put(fmt.Sprintf("%v/!seo_setting/versions", res.ToParam()), controller.RestoreVersion)
+		router.Get(fmt.Sprintf("%v/!seo_settings/export", res.ToParam()), controller.Export)
+		router.Get(fmt.Sprintf("%v/!seo_settings/import", res.ToParam()), controller.ImportPage)
+		router.Post(fmt.Sprintf("%v/!seo_settings/import", res.ToParam()), controller.Import)
+		router.Put(fmt.Sprintf("%v/!seo_settings/orphaned", res.ToParam()), controller.Orphaned)
+		router.Get(fmt.Sprintf("%v/!audit", res.ToParam()), controller.Audit)
+		router.Post(fmt.Sprintf("%v/!audit", res.ToParam()), controller.RunAudit)
+		router.Get(fmt.Sprintf("%v/!audit/export", res.ToParam()), controller.AuditExport)
 
 		registerFuncMap(Admin)
 	}
 }
 
+// configuredResources resources shared by collections that have been configured
+var configuredResources sync.Map
+
+// sharedResource return res, or the resource of value that is already added to admin, it is only added and configured once
+func sharedResource(Admin *admin.Admin, res *admin.Resource, value interface{}, config *admin.Config, configure func(*admin.Resource)) *admin.Resource {
+	if res == nil {
+		if res = Admin.GetResource(reflect.TypeOf(value).Elem().String()); res == nil {
+			res = Admin.AddResource(value, config)
+		}
+	}
+
+	if _, configured := configuredResources.LoadOrStore(res, true); !configured {
+		configure(res)
+	}
+	return res
+}
+
 // Helpers
+var variableRegexp = regexp.MustCompile("{{([a-zA-Z0-9]*)}}")
+
+// parseSEOTag parse `seo` struct tag like `seo:"type:CategoryPage;url:/categories/{{Code}}"`
+func parseSEOTag(tag string) map[string]string {
+	settings := map[string]string{}
+	for _, part := range strings.Split(tag, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		pair := strings.SplitN(part, ":", 2)
+		if len(pair) == 2 {
+			settings[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+		} else {
+			settings[pair[0]] = ""
+		}
+	}
+	return settings
+}
+
+// fillVariables replace variables like {{Name}} of str with values
+func fillVariables(str string, values map[string]string) string {
+	matches := variableRegexp.FindAllStringSubmatch(str, -1)
+	for _, match := range matches {
+		str = strings.Replace(str, match[0], values[match[1]], 1)
+	}
+	return str
+}
+
 func replaceTags(seoSetting Setting, validTags []string, values map[string]string) Setting {
 	replace := func(str string) string {
-		re := regexp.MustCompile("{{([a-zA-Z0-9]*)}}")
-		matches := re.FindAllStringSubmatch(str, -1)
-		for _, match := range matches {
-			str = strings.Replace(str, match[0], values[match[1]], 1)
-		}
-		return str
+		return fillVariables(str, values)
 	}
 
 	seoSetting.Title = replace(seoSetting.Title)
@@ -201,11 +360,15 @@ func replaceTags(seoSetting Setting, validTags []string, values map[string]strin
 	seoSetting.OpenGraphURL = replace(seoSetting.OpenGraphURL)
 	seoSetting.OpenGraphImageURL = replace(seoSetting.OpenGraphImageURL)
 	seoSetting.OpenGraphType = replace(seoSetting.OpenGraphType)
-	for idx, metadata := range seoSetting.OpenGraphMetadata {
-		seoSetting.OpenGraphMetadata[idx] = OpenGraphMetadata{
-			Property: replace(metadata.Property),
-			Content:  replace(metadata.Content),
-		}
+	// metadata is copied as settings are shared by records, e.g: page setting cached when auditing records
+	var metadata []OpenGraphMetadata
+	for _, m := range seoSetting.OpenGraphMetadata {
+		metadata = append(metadata, OpenGraphMetadata{
+			Property: replace(m.Property),
+			Content:  replace(m.Content),
+		})
 	}
+	seoSetting.OpenGraphMetadata = metadata
+	seoSetting.OpenGraphObject = seoSetting.OpenGraphObject.replace(replace)
 	return seoSetting
 }
@@ -2,17 +2,22 @@ package seo
 
 import (
 	"bytes"
+	"crypto/rand"
 	"database/sql/driver"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"html/template"
-	"log"
 	"net/url"
+	"reflect"
 	"time"
 
 	"github.com/qor/admin"
 	"github.com/qor/media/media_library"
 	"github.com/qor/qor"
 	"github.com/qor/qor/resource"
+	"github.com/qor/qor/utils"
+	"github.com/qor/validations"
 )
 
 // QorSEOSettingInterface support customize Seo model
@@ -39,12 +44,46 @@ type QorSEOSettingInterface interface {
 	GetOpenGraphMetadata() []OpenGraphMetadata
 }
 
+// QorSEOSettingSetterInterface support replacing setting of customized seo model, which is required to restore versions,
+// import settings, move content of orphaned settings and save drafts
+type QorSEOSettingSetterInterface interface {
+	SetSEOSetting(Setting)
+}
+
+// setSEOSetting set setting of a seo model, return an error if the model doesn't implement QorSEOSettingSetterInterface
+func setSEOSetting(record interface{}, setting Setting) error {
+	setter, ok := record.(QorSEOSettingSetterInterface)
+	if !ok {
+		return fmt.Errorf("seo: setting model %T doesn't implement SetSEOSetting", record)
+	}
+	setter.SetSEOSetting(setting)
+	return nil
+}
+
+// QorSEOSettingDraftInterface support draft, preview and scheduled publishing for customized seo model
+type QorSEOSettingDraftInterface interface {
+	GetDraft() (Setting, bool)
+	SetDraft(setting Setting, publishAt *time.Time)
+	GetPublishAt() *time.Time
+	GetPreviewToken() string
+	PublishDraft()
+	DiscardDraft()
+}
+
+// PreviewTokenParam query param used to preview seo drafts on front end, e.g. /products/1?seo_preview=<preview token>
+const PreviewTokenParam = "seo_preview"
+
 // QorSEOSetting default seo model
 type QorSEOSetting struct {
 	Name        string `gorm:"primary_key"`
 	Setting     Setting
 	IsGlobalSEO bool
 
+	Draft        Setting
+	HasDraft     bool
+	PublishAt    *time.Time
+	PreviewToken string
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time `gorm:"index"`
@@ -65,8 +104,14 @@ type Setting struct {
 	OpenGraphImageURL              string
 	OpenGraphImageFromMediaLibrary media_library.MediaBox
 	OpenGraphMetadata              []OpenGraphMetadata
+	OpenGraphObject                OpenGraphObject
 	EnabledCustomize               bool
-	GlobalSetting                  map[string]string
+	// CustomizedFields fields customized by a record, other fields are inherited from page setting, see CustomizableFields
+	CustomizedFields []string
+	GlobalSetting    map[string]string
+
+	// decodeErr error of decoding the stored value, it is returned by GetSEOSettingE and RenderE rather than failing queries
+	decodeErr error
 }
 
 // OpenGraphMetadata open graph meta data
@@ -80,6 +125,54 @@ func (s QorSEOSetting) GetSEOSetting() Setting {
 	return s.Setting
 }
 
+// SetSEOSetting set seo setting
+func (s *QorSEOSetting) SetSEOSetting(setting Setting) {
+	s.Setting = setting
+}
+
+// GetDraft get unpublished setting
+func (s QorSEOSetting) GetDraft() (Setting, bool) {
+	return s.Draft, s.HasDraft
+}
+
+// SetDraft save setting as draft, it will be published at publishAt if it is present
+func (s *QorSEOSetting) SetDraft(setting Setting, publishAt *time.Time) {
+	s.Draft = setting
+	s.HasDraft = true
+	s.PublishAt = publishAt
+	if s.PreviewToken == "" {
+		token := make([]byte, 16)
+		rand.Read(token)
+		s.PreviewToken = hex.EncodeToString(token)
+	}
+}
+
+// GetPublishAt get draft's scheduled publish time
+func (s QorSEOSetting) GetPublishAt() *time.Time {
+	return s.PublishAt
+}
+
+// GetPreviewToken get token used to preview draft on front end
+func (s QorSEOSetting) GetPreviewToken() string {
+	return s.PreviewToken
+}
+
+// PublishDraft replace setting with draft
+func (s *QorSEOSetting) PublishDraft() {
+	if s.HasDraft {
+		s.Setting = s.Draft
+	}
+	s.DiscardDraft()
+}
+
+// DiscardDraft remove draft
+func (s *QorSEOSetting) DiscardDraft() {
+	s.Draft = Setting{}
+	s.HasDraft = false
+	s.PublishAt = nil
+	s.PreviewToken = ""
+}
+
 // GetName get QorSeoSetting's name
 func (s QorSEOSetting) GetName() string {
 	return s.Name
@@ -167,15 +260,26 @@ func (s QorSEOSetting) GetSEO() *SEO {
 	return s.collection.GetSEO(s.Name)
 }
 
-// Scan scan value from database into struct
+// Scan scan value from database into struct, invalid values are ignored like before, their errors are returned by GetSEOSettingE and RenderE as *SettingDecodeError
 func (setting *Setting) Scan(value interface{}) error {
+	setting.decodeErr = nil
+
+	var values []string
 	if bytes, ok := value.([]byte); ok {
-		json.Unmarshal(bytes, setting)
+		values = []string{string(bytes)}
 	} else if str, ok := value.(string); ok {
-		json.Unmarshal([]byte(str), setting)
+		values = []string{str}
 	} else if strs, ok := value.([]string); ok {
-		for _, str := range strs {
-			json.Unmarshal([]byte(str), setting)
+		values = strs
+	}
+
+	for _, str := range values {
+		if str == "" {
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(str), setting); err != nil {
+			setting.decodeErr = &SettingDecodeError{Err: err}
 		}
 	}
 	return nil
@@ -187,66 +291,51 @@ func (setting Setting) Value() (driver.Value, error) {
 	return string(result), err
 }
 
-// FormattedHTML return formated seo setting as HTML
+// FormattedHTML return formated seo setting as HTML, errors are logged
 func (setting Setting) FormattedHTML(context *qor.Context) template.HTML {
-	toAbsoluteURL := func(str string) string {
-		if u, err := url.Parse(str); err == nil {
-			if u.IsAbs() {
-				return str
-			}
-
-			if u.Host == "" && context.Request != nil {
-				u.Host = context.Request.Host
-			}
-
-			if u.Scheme == "" {
-				if context.Request != nil && context.Request.URL.Scheme != "" {
-					u.Scheme = context.Request.URL.Scheme
-				} else {
-					u.Scheme = "http"
-				}
-			}
-			return u.String()
-		}
-		return ""
+	result, err := setting.formattedHTML(context)
+	if err != nil {
+		logError(context, err)
 	}
+	return result
+}
 
-	openGraphData := map[string]string{}
+// formattedHTML render the setting, defaults replace properties rendered from the setting, OpenGraphMetadata replaces both of them
+func (setting Setting) formattedHTML(context *qor.Context, defaults ...OpenGraphMetadata) (template.HTML, error) {
+	toAbsoluteURL := func(str string) string {
+		return absoluteURL(context, str)
+	}
 
-	if setting.OpenGraphURL != "" {
-		openGraphData["og:url"] = toAbsoluteURL(setting.OpenGraphURL)
+	title, desc := setting.Title, setting.Description
+	if setting.OpenGraphTitle != "" {
+		title = setting.OpenGraphTitle
 	}
 
-	if setting.OpenGraphType != "" {
-		openGraphData["og:type"] = setting.OpenGraphType
+	if setting.OpenGraphDescription != "" {
+		desc = setting.OpenGraphDescription
 	}
 
+	var image string
 	if len(setting.OpenGraphImageFromMediaLibrary.Files) > 0 {
-		openGraphData["og:image"] = toAbsoluteURL(setting.OpenGraphImageFromMediaLibrary.URL())
+		image = toAbsoluteURL(setting.OpenGraphImageFromMediaLibrary.URL())
 	} else if setting.OpenGraphImageURL != "" {
-		openGraphData["og:image"] = toAbsoluteURL(setting.OpenGraphImageURL)
-	}
-
-	for _, metavalue := range setting.OpenGraphMetadata {
-		openGraphData[metavalue.Property] = metavalue.Content
+		image = toAbsoluteURL(setting.OpenGraphImageURL)
 	}
 
-	if _, ok := openGraphData["og:title"]; !ok {
-		title := setting.Title
-		if setting.OpenGraphTitle != "" {
-			title = setting.OpenGraphTitle
-		}
-		openGraphData["og:title"] = title
+	var ogURL string
+	if setting.OpenGraphURL != "" {
+		ogURL = toAbsoluteURL(setting.OpenGraphURL)
 	}
 
-	if _, ok := openGraphData["og:description"]; !ok {
-		desc := setting.Description
-		if setting.OpenGraphDescription != "" {
-			desc = setting.OpenGraphDescription
-		}
-
-		openGraphData["og:description"] = desc
-	}
+	// properties of OpenGraphMetadata replace default properties, default properties are kept in the order they were rendered in,
+	// followed by properties of the vertical og:type
+	openGraphData := OpenGraphProperties{
+		{Property: "og:description", Content: desc},
+		{Property: "og:image", Content: image},
+		{Property: "og:title", Content: title},
+		{Property: "og:type", Content: setting.OpenGraphType},
+		{Property: "og:url", Content: ogURL},
+	}.Merge(setting.OpenGraphObject.Properties(setting.OpenGraphType)).Merge(defaults).Merge(setting.OpenGraphMetadata)
 
 	var buf bytes.Buffer
 	err := seoTmpl.Execute(&buf, map[string]interface{}{
@@ -256,24 +345,42 @@ func (setting Setting) FormattedHTML(context *qor.Context) template.HTML {
 		"ogs":         openGraphData,
 	})
 	if err != nil {
-		var requestURL string
-		if context != nil && context.Request != nil && context.Request.URL != nil {
-			requestURL = context.Request.URL.String()
-		}
-		log.Printf("Error: exec seo tmpl has err (%v) in %s", err, requestURL)
-		return ""
+		return "", &TemplateError{Name: "seo tags", Err: err}
 	}
 
-	return template.HTML(buf.String())
+	return template.HTML(buf.String()), nil
+}
+
+// absoluteURL complete scheme and host of str with current request
+func absoluteURL(context *qor.Context, str string) string {
+	if u, err := url.Parse(str); err == nil {
+		if u.IsAbs() {
+			return str
+		}
+
+		if u.Host == "" && context.Request != nil {
+			u.Host = context.Request.Host
+		}
+
+		if u.Scheme == "" {
+			if context.Request != nil && context.Request.URL.Scheme != "" {
+				u.Scheme = context.Request.URL.Scheme
+			} else {
+				u.Scheme = "http"
+			}
+		}
+		return u.String()
+	}
+	return ""
 }
 
 var seoTmpl = template.Must(
 	template.New("seo_tmpl").Parse(`<title>{{.title}}</title>
 <meta name="description" content="{{.description}}">
 <meta name="keywords" content="{{.keywords}}">
-{{range $key, $val := .ogs -}}
-{{if ne $val "" -}}
-<meta property="{{$key}}" name="{{$key}}" content="{{$val}}">
+{{range .ogs -}}
+{{if ne .Content "" -}}
+<meta property="{{.Property}}" name="{{.Property}}" content="{{.Content}}">
 {{end -}}
 {{end -}}`),
 )
@@ -284,6 +391,24 @@ func (setting Setting) ConfigureQorMetaBeforeInitialize(meta resource.Metaor) {
 		meta.Type = "seo"
 		if res, ok := meta.GetBaseResource().(*admin.Resource); ok {
 			res.UseTheme("seo_meta")
+			res.AddProcessor(&resource.Processor{
+				Name: "seo:crop_open_graph_image:" + meta.Name,
+				Handler: func(record interface{}, metaValues *resource.MetaValues, context *qor.Context) error {
+					return cropOpenGraphImageOfField(res.GetAdmin(), record, meta.GetFieldName(), context.GetDB())
+				},
+			})
+			res.AddProcessor(&resource.Processor{
+				Name: "seo:validate_open_graph_properties:" + meta.Name,
+				Handler: func(record interface{}, metaValues *resource.MetaValues, context *qor.Context) error {
+					field := reflect.Indirect(reflect.ValueOf(record)).FieldByName(meta.GetFieldName())
+					if setting, ok := field.Interface().(Setting); ok {
+						if err := setting.validateOpenGraphProperties(); err != nil {
+							return validations.NewError(record, meta.GetFieldName(), err.Error())
+						}
+					}
+					return nil
+				},
+			})
 		}
 	}
 }
@@ -296,6 +421,9 @@ func (setting Setting) ConfigureQorResource(res resource.Resourcer) {
 		res.Meta(&admin.Meta{Name: "Keywords", Label: "Meta Keywords"})
 		res.Meta(&admin.Meta{Name: "Type", Type: "hidden"})
 		res.Meta(&admin.Meta{Name: "EnabledCustomize", Type: "hidden"})
+		res.Meta(&admin.Meta{Name: "CustomizedFields", Type: "hidden", Setter: func(record interface{}, metaValue *resource.MetaValue, context *qor.Context) {
+			record.(*Setting).setCustomizedFields(utils.ToArray(metaValue.Value))
+		}})
 		res.Meta(&admin.Meta{Name: "OpenGraphImageFromMediaLibrary", Label: "Open Graph Image", Config: &media_library.MediaBoxConfig{
 			Max:       1,
 			AllowType: media_library.ALLOW_TYPE_IMAGE,
@@ -305,6 +433,15 @@ func (setting Setting) ConfigureQorResource(res resource.Resourcer) {
 		metadataResource.NewAttrs(&admin.Section{Rows: [][]string{{"Property", "Content"}}})
 		metadataResource.EditAttrs(&admin.Section{Rows: [][]string{{"Property", "Content"}}})
 
+		objectResource := res.Meta(&admin.Meta{Name: "OpenGraphObject", Label: "Open Graph Type Properties"}).Resource
+		objectResource.Meta(&admin.Meta{Name: "Song", Label: "Music Song"})
+		objectResource.EditAttrs(
+			&admin.Section{Title: "Product", Rows: [][]string{{"Product"}}},
+			&admin.Section{Title: "Video Movie & Episode", Rows: [][]string{{"Video"}}},
+			&admin.Section{Title: "Music Song", Rows: [][]string{{"Song"}}},
+			&admin.Section{Title: "Book", Rows: [][]string{{"Book"}}},
+		)
+
 		res.EditAttrs(
 			&admin.Section{
 				Title: "Basic",
@@ -318,7 +455,11 @@ func (setting Setting) ConfigureQorResource(res resource.Resourcer) {
 					{"OpenGraphImageURL", "OpenGraphImageFromMediaLibrary"}, {"OpenGraphMetadata"},
 				},
 			},
-			"Type", "EnabledCustomize",
+			&admin.Section{
+				Title: "Open Graph Type Properties",
+				Rows:  [][]string{{"OpenGraphObject"}},
+			},
+			"Type",
 		)
 	}
 }
@@ -0,0 +1,169 @@
+package seo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/qor"
+)
+
+// QorSEOSettingVersion a snapshot of a seo setting, saved every time the setting is changed from admin
+type QorSEOSettingVersion struct {
+	ID           uint   `gorm:"primary_key"`
+	Name         string `gorm:"index"`
+	Setting      Setting
+	IsGlobalSEO  bool
+	CreatedBy    string
+	RestoredFrom uint
+	CreatedAt    time.Time
+}
+
+// SettingChange a changed field between two versions of a seo setting
+type SettingChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Diff return changed fields from previous version
+func (version QorSEOSettingVersion) Diff(previous QorSEOSettingVersion) []SettingChange {
+	return diffSettings(previous.Setting, version.Setting)
+}
+
+// Versions return saved versions of a seo setting, newest first
+func (collection *Collection) Versions(context *qor.Context, name string) (versions []QorSEOSettingVersion, err error) {
+	err = context.GetDB().Where("name = ?", name).Order("id DESC").Find(&versions).Error
+	return versions, err
+}
+
+// RestoreVersion restore a seo setting to given version, the restore is saved as a new version
+func (collection *Collection) RestoreVersion(context *qor.Context, id uint) error {
+	var (
+		db      = context.GetDB()
+		version QorSEOSettingVersion
+	)
+
+	if err := db.First(&version, id).Error; err != nil {
+		return err
+	}
+
+	// the restored setting and its new version are saved together
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := collection.SettingResource.NewStruct()
+		if err := tx.Where("name = ?", version.Name).First(result).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		setting := result.(QorSEOSettingInterface)
+		setting.SetName(version.Name)
+		if err := setSEOSetting(result, version.Setting); err != nil {
+			return err
+		}
+		setting.SetIsGlobalSEO(version.IsGlobalSEO)
+		if err := tx.Save(result).Error; err != nil {
+			return err
+		}
+
+		txContext := context.Clone()
+		txContext.DB = tx
+		return collection.saveVersion(txContext, setting, version.ID)
+	})
+}
+
+// saveVersion save current state of a seo setting as a new version
+func (collection *Collection) saveVersion(context *qor.Context, setting QorSEOSettingInterface, restoredFrom uint) error {
+	version := QorSEOSettingVersion{
+		Name:         setting.GetName(),
+		Setting:      setting.GetSEOSetting(),
+		IsGlobalSEO:  setting.GetIsGlobalSEO(),
+		RestoredFrom: restoredFrom,
+	}
+
+	if context.CurrentUser != nil {
+		version.CreatedBy = context.CurrentUser.DisplayName()
+	}
+
+	return context.GetDB().Create(&version).Error
+}
+
+// diffSettings compare fields of two settings, global setting, open graph metadata and properties of open graph types are compared by key
+func diffSettings(before, after Setting) (changes []SettingChange) {
+	beforeValue, afterValue := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := 0; i < beforeValue.NumField(); i++ {
+		field := beforeValue.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		switch name {
+		case "GlobalSetting":
+			changes = append(changes, diffMaps(name, before.GlobalSetting, after.GlobalSetting)...)
+		case "OpenGraphMetadata":
+			changes = append(changes, diffMaps(name, openGraphMetadataMap(before.OpenGraphMetadata), openGraphMetadataMap(after.OpenGraphMetadata))...)
+		case "OpenGraphObject":
+			changes = append(changes, diffMaps(name, openGraphObjectMap(before.OpenGraphObject), openGraphObjectMap(after.OpenGraphObject))...)
+		default:
+			if b, a := formatSettingField(beforeValue.Field(i)), formatSettingField(afterValue.Field(i)); b != a {
+				changes = append(changes, SettingChange{Field: name, Before: b, After: a})
+			}
+		}
+	}
+	return changes
+}
+
+func diffMaps(prefix string, before, after map[string]string) (changes []SettingChange) {
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		if before[key] != after[key] {
+			changes = append(changes, SettingChange{Field: prefix + "." + key, Before: before[key], After: after[key]})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func openGraphMetadataMap(metadata []OpenGraphMetadata) map[string]string {
+	results := map[string]string{}
+	for _, m := range metadata {
+		if value, ok := results[m.Property]; ok {
+			results[m.Property] = strings.Join([]string{value, m.Content}, ", ")
+		} else {
+			results[m.Property] = m.Content
+		}
+	}
+	return results
+}
+
+// openGraphObjectMap return fields of the object by names like Product.PriceAmount
+func openGraphObjectMap(object OpenGraphObject) map[string]string {
+	results := map[string]string{}
+	value := reflect.ValueOf(object)
+	for i := 0; i < value.NumField(); i++ {
+		for j := 0; j < value.Field(i).NumField(); j++ {
+			if content := value.Field(i).Field(j).String(); content != "" {
+				results[value.Type().Field(i).Name+"."+value.Field(i).Type().Field(j).Name] = content
+			}
+		}
+	}
+	return results
+}
+
+func formatSettingField(value reflect.Value) string {
+	if urler, ok := value.Interface().(interface{ URL(...string) string }); ok {
+		return urler.URL()
+	}
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,180 @@
+package seo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/qor"
+)
+
+func TestSEOSettingVersions(t *testing.T) {
+	setupSeoCollection()
+	db.Delete(&QorSEOSettingVersion{})
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	for _, title := range []string{"first title", "second title"} {
+		form := url.Values{
+			"_method":                   {"PUT"},
+			"QorResource.Setting.Title": {title},
+			"QorResource.Setting.Type":  {"CategoryPage"},
+		}
+		if _, err := http.PostForm(server.URL+collection.SEOSettingURL("CategoryPage"), form); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	context := &qor.Context{DB: db}
+	versions, err := collection.Versions(context, "CategoryPage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(versions) != 2 || versions[0].Setting.Title != "second title" || versions[1].Setting.Title != "first title" {
+		t.Fatalf("should save a version for every update, but got %#v", versions)
+	}
+
+	changes := versions[0].Diff(versions[1])
+	if len(changes) != 1 || changes[0] != (SettingChange{Field: "Title", Before: "first title", After: "second title"}) {
+		t.Errorf("diff of versions is not correct, got %#v", changes)
+	}
+
+	if _, err := http.PostForm(server.URL+collection.SEOSettingVersionsURL("CategoryPage"), url.Values{"_method": {"PUT"}, "version": {fmt.Sprint(versions[1].ID)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var setting QorSEOSetting
+	db.First(&setting, "name = ?", "CategoryPage")
+	if setting.Setting.Title != "first title" {
+		t.Errorf("setting should be restored to first version, but got %v", setting.Setting.Title)
+	}
+
+	if versions, _ = collection.Versions(context, "CategoryPage"); len(versions) != 3 || versions[0].RestoredFrom != versions[2].ID {
+		t.Errorf("restore should be saved as a new version, but got %#v", versions)
+	}
+}
+
+func TestDiffSettings(t *testing.T) {
+	before := Setting{
+		Title:             "title",
+		OpenGraphMetadata: []OpenGraphMetadata{{Property: "og:locale", Content: "en_US"}},
+		GlobalSetting:     map[string]string{"SiteName": "Qor"},
+	}
+	after := Setting{
+		Title:             "title",
+		Description:       "description",
+		OpenGraphMetadata: []OpenGraphMetadata{{Property: "og:locale", Content: "ja_JP"}},
+		GlobalSetting:     map[string]string{"SiteName": "Qor", "BrandName": "Qor"},
+		EnabledCustomize:  true,
+	}
+
+	expects := []SettingChange{
+		{Field: "Description", Before: "", After: "description"},
+		{Field: "OpenGraphMetadata.og:locale", Before: "en_US", After: "ja_JP"},
+		{Field: "EnabledCustomize", Before: "false", After: "true"},
+		{Field: "GlobalSetting.BrandName", Before: "", After: "Qor"},
+	}
+
+	changes := diffSettings(before, after)
+	if len(changes) != len(expects) {
+		t.Fatalf("expect changes %#v, but got %#v", expects, changes)
+	}
+
+	for i, change := range changes {
+		if change != expects[i] {
+			t.Errorf("expect change %#v, but got %#v", expects[i], change)
+		}
+	}
+}
+
+// legacySEOSetting a custom seo model implements QorSEOSettingInterface without SetSEOSetting
+type legacySEOSetting struct {
+	QorSEOSettingInterface
+}
+
+func TestSetSEOSettingOfLegacyModel(t *testing.T) {
+	if err := setSEOSetting(&legacySEOSetting{QorSEOSettingInterface: &QorSEOSetting{}}, Setting{Title: "Title"}); err == nil {
+		t.Errorf("models without SetSEOSetting should get an error")
+	}
+
+	setting := &QorSEOSetting{}
+	if err := setSEOSetting(setting, Setting{Title: "Title"}); err != nil || setting.Setting.Title != "Title" {
+		t.Errorf("setting should be set, but got %v, %v", setting.Setting, err)
+	}
+}
+
+func TestRestoreVersionErrors(t *testing.T) {
+	setupSeoCollection()
+	db.Delete(&QorSEOSettingVersion{})
+	context := &qor.Context{DB: db}
+
+	setting := QorSEOSetting{Name: "CategoryPage", Setting: Setting{Title: "current title"}}
+	db.Create(&setting)
+	version := QorSEOSettingVersion{Name: "CategoryPage", Setting: Setting{Title: "old title"}}
+	db.Create(&version)
+
+	failOn := func(processor *gorm.CallbackProcessor, name string, table string) {
+		processor.After("gorm:"+strings.ToLower(name)).Register("seo_test:fail", func(scope *gorm.Scope) {
+			if scope.TableName() == table {
+				scope.Err(errors.New("failed"))
+			}
+		})
+	}
+
+	failOn(db.Callback().Query(), "query", "qor_seo_settings")
+	err := collection.RestoreVersion(context, version.ID)
+	db.Callback().Query().Remove("seo_test:fail")
+	if err == nil {
+		t.Errorf("errors of loading setting should be returned")
+	}
+
+	failOn(db.Callback().Create(), "create", "qor_seo_setting_versions")
+	err = collection.RestoreVersion(context, version.ID)
+	db.Callback().Create().Remove("seo_test:fail")
+	if err == nil {
+		t.Errorf("errors of saving version should be returned")
+	}
+
+	var count int
+	db.Model(&QorSEOSetting{}).Where("name = ?", "CategoryPage").Count(&count)
+	db.First(&setting, "name = ?", "CategoryPage")
+	if count != 1 || setting.Setting.Title != "current title" {
+		t.Errorf("setting should not be changed if restore failed, but got %v settings with %v", count, setting.Setting.Title)
+	}
+}
+
+func TestUpdateRollbackIfVersionFailed(t *testing.T) {
+	setupSeoCollection()
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	db.Create(&QorSEOSetting{Name: "CategoryPage", Setting: Setting{Title: "current title"}})
+
+	db.Callback().Create().After("gorm:create").Register("seo_test:fail", func(scope *gorm.Scope) {
+		if scope.TableName() == "qor_seo_setting_versions" {
+			scope.Err(errors.New("failed"))
+		}
+	})
+	defer db.Callback().Create().Remove("seo_test:fail")
+
+	form := url.Values{
+		"_method":                   {"PUT"},
+		"QorResource.Setting.Title": {"new title"},
+		"QorResource.Setting.Type":  {"CategoryPage"},
+	}
+	if _, err := http.PostForm(server.URL+collection.SEOSettingURL("CategoryPage"), form); err != nil {
+		t.Fatal(err)
+	}
+
+	var setting QorSEOSetting
+	db.First(&setting, "name = ?", "CategoryPage")
+	if setting.Setting.Title != "current title" {
+		t.Errorf("setting should not be saved if its version failed to save, but got %v", setting.Setting.Title)
+	}
+}
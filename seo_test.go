@@ -24,7 +24,7 @@ var collection *Collection
 
 func init() {
 	db = utils.TestDB()
-	db.AutoMigrate(&QorSEOSetting{})
+	db.AutoMigrate(&QorSEOSetting{}, &QorSEOSettingVersion{}, &QorSEORedirect{}, &QorSEONotFound{})
 }
 
 // Modal
@@ -61,6 +61,7 @@ type SeoAppendDefaultValueTestCase struct {
 	CatDescription      string
 	CatKeywords         string
 	CatEnabledCustomize bool
+	CatCustomizedFields []string
 	ExpectTitle         string
 	ExpectDescription   string
 	ExpectKeywords      string
@@ -191,35 +192,34 @@ func TestSeoSections(t *testing.T) {
 		t.Errorf(color.RedString("\nSeoSections TestCase #1: should get empty settings"))
 	}
 
-	seoSections(&admin.Context{Context: &qor.Context{DB: db}}, collection)
+	sections := seoSections(&admin.Context{Context: &qor.Context{DB: db}}, collection)
 	db.Model(QorSEOSetting{}).Count(&count)
-	if count != 2 {
-		t.Errorf(color.RedString("\nSeoSections TestCase #2: should get two settings"))
+	if count != 0 {
+		t.Errorf(color.RedString("\nSeoSections TestCase #2: should not save settings when rendering"))
 	}
 
-	var settings []QorSEOSetting
-	settingNames := []string{"CategoryPage", "DefaultPage"}
-	db.Model(QorSEOSetting{}).Order("Name ASC").Find(&settings)
-	for i, setting := range settings {
-		if setting.Name != settingNames[i] {
-			t.Errorf(color.RedString(fmt.Sprintf("\nSeoSections TestCase #%v: should has setting `%v`", 3+i, settingNames[i])))
+	settingNames := []string{"DefaultPage", "CategoryPage"}
+	if len(sections) != 2 {
+		t.Errorf(color.RedString("\nSeoSections TestCase #3: should get two settings"))
+	}
+	for i, section := range sections {
+		if section.(QorSEOSettingInterface).GetName() != settingNames[i] {
+			t.Errorf(color.RedString(fmt.Sprintf("\nSeoSections TestCase #%v: should has setting `%v`", 4+i, settingNames[i])))
 		}
 	}
 }
 
 func TestSeoGlobalSetting(t *testing.T) {
 	setupSeoCollection()
+	setting := seoGlobalSetting(&admin.Context{Context: &qor.Context{DB: db}}, collection).(QorSEOSettingInterface)
+	if setting.GetName() != "Seo" || !setting.GetIsGlobalSEO() {
+		t.Errorf(color.RedString("\nSeoGlobalSetting TestCase #1: global setting should be present"))
+	}
+
 	var count int
 	db.Model(QorSEOSetting{}).Count(&count)
 	if count != 0 {
-		t.Errorf(color.RedString("\nSeoGlobalSetting TestCase #1: global setting should be empty"))
-	}
-
-	seoGlobalSetting(&admin.Context{Context: &qor.Context{DB: db}}, collection)
-	var settings []QorSEOSetting
-	db.Find(&settings)
-	if len(settings) != 1 || !settings[0].IsGlobalSEO {
-		t.Errorf(color.RedString("\nSeoGlobalSetting TestCase #2: global setting should be present"))
+		t.Errorf(color.RedString("\nSeoGlobalSetting TestCase #2: should not save global setting when rendering"))
 	}
 }
 
@@ -237,12 +237,14 @@ func TestSeoAppendDefaultValue(t *testing.T) {
 	createCategoryPageSetting(Setting{Title: "GT", Description: "GD", Keywords: "GK"})
 	testCases := []SeoAppendDefaultValueTestCase{
 		{CatTitle: "T", CatDescription: "D", CatKeywords: "K", CatEnabledCustomize: true, ExpectTitle: "T", ExpectDescription: "D", ExpectKeywords: "K"},
-		{CatTitle: "T", CatDescription: "D", CatKeywords: "K", CatEnabledCustomize: false, ExpectTitle: "T", ExpectDescription: "D", ExpectKeywords: "K"},
+		// fields are not customized, values of page setting are rendered
+		{CatTitle: "T", CatDescription: "D", CatKeywords: "K", CatEnabledCustomize: false, ExpectTitle: "GT", ExpectDescription: "GD", ExpectKeywords: "GK"},
 		{CatTitle: "", CatDescription: "", CatKeywords: "", CatEnabledCustomize: true, ExpectTitle: "", ExpectDescription: "", ExpectKeywords: ""},
 		{CatTitle: "", CatDescription: "", CatKeywords: "", CatEnabledCustomize: false, ExpectTitle: "GT", ExpectDescription: "GD", ExpectKeywords: "GK"},
+		{CatTitle: "T", CatDescription: "", CatKeywords: "K", CatEnabledCustomize: true, CatCustomizedFields: []string{"Title"}, ExpectTitle: "T", ExpectDescription: "GD", ExpectKeywords: "GK"},
 	}
 	for i, testCase := range testCases {
-		category := Category{SEO: Setting{Title: testCase.CatTitle, Description: testCase.CatDescription, Keywords: testCase.CatKeywords, EnabledCustomize: testCase.CatEnabledCustomize}}
+		category := Category{SEO: Setting{Title: testCase.CatTitle, Description: testCase.CatDescription, Keywords: testCase.CatKeywords, EnabledCustomize: testCase.CatEnabledCustomize, CustomizedFields: testCase.CatCustomizedFields}}
 		seo := collection.GetSEO("CategoryPage")
 		setting := seoAppendDefaultValue(&admin.Context{Context: &qor.Context{DB: db}}, seo, category.SEO).(Setting)
 		var hasError bool
@@ -362,12 +364,12 @@ func TestSeoTmpl(t *testing.T) {
 		"title":       "<br>title",
 		"description": `<script>alert("exec");</script>description`,
 		"keywords":    `<meta name="keywords" content="keywords">keywords`,
-		"ogs": map[string]string{
-			"og:url":         "http://example_test.test/  a/  b/",
-			"og:title":       "<span>title</span>",
-			"og:type":        "type<br>",
-			"og:description": "",
-			"og:image":       "http://example_test.test/  a/  b.jpg",
+		"ogs": OpenGraphProperties{
+			{Property: "og:description", Content: ""},
+			{Property: "og:image", Content: "http://example_test.test/  a/  b.jpg"},
+			{Property: "og:title", Content: "<span>title</span>"},
+			{Property: "og:type", Content: "type<br>"},
+			{Property: "og:url", Content: "http://example_test.test/  a/  b/"},
 		},
 	})
 	if err != nil {
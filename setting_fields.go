@@ -0,0 +1,123 @@
+package seo
+
+import (
+	"reflect"
+
+	"github.com/qor/admin"
+)
+
+// settingField a Setting field of a record, SEO is the seo name bound with `seo:"type:ProductPage"` tag, blank if not tagged
+type settingField struct {
+	Name    string
+	SEO     string
+	Setting Setting
+}
+
+// settingFieldsOf return Setting fields of a record
+func settingFieldsOf(record interface{}) (fields []settingField) {
+	value := reflect.Indirect(reflect.ValueOf(record))
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		if field := value.Type().Field(i); field.Type == reflect.TypeOf(Setting{}) && value.Field(i).CanInterface() {
+			fields = append(fields, settingField{Name: field.Name, SEO: parseSEOTag(field.Tag.Get("seo"))["type"], Setting: value.Field(i).Interface().(Setting)})
+		}
+	}
+	return fields
+}
+
+// settingFieldFor return the Setting field of a record that is rendered with seo name,
+// it is the field tagged with the name, or the first field without tag
+func settingFieldFor(record interface{}, name string) (Setting, bool) {
+	fields := settingFieldsOf(record)
+	for _, field := range fields {
+		if field.SEO == name {
+			return field.Setting, true
+		}
+	}
+
+	if current := currentSeoFieldOf(record); current != "" {
+		for _, field := range fields {
+			if field.Name == current {
+				return field.Setting, true
+			}
+		}
+	}
+
+	for _, field := range fields {
+		if field.SEO == "" {
+			return field.Setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// currentSeoFieldIndicator records with multiple Setting fields could set name of the field to render to CurrentSeoField.
+// Deprecated: bind Setting fields to seos with `seo:"type:ProductPage"` tag
+const currentSeoFieldIndicator = "CurrentSeoField"
+
+// currentSeoFieldOf return value of CurrentSeoField of a record
+func currentSeoFieldOf(record interface{}) string {
+	if value := reflect.Indirect(reflect.ValueOf(record)); value.Kind() == reflect.Struct {
+		if field := value.FieldByName(currentSeoFieldIndicator); field.IsValid() && field.Kind() == reflect.String {
+			return field.String()
+		}
+	}
+	return ""
+}
+
+// seoOfMeta return seo of a Setting meta, from `seo` tag of the field, or GetSEO of the record
+func seoOfMeta(context *admin.Context, record interface{}, meta *admin.Meta) *SEO {
+	fieldName := meta.FieldName
+	if fieldName == "" {
+		fieldName = meta.Name
+	}
+	return seoOfField(context.Admin, record, fieldName)
+}
+
+// seoOfField return seo of a Setting field, seo of page settings is found by their names
+func seoOfField(qorAdmin *admin.Admin, record interface{}, fieldName string) *SEO {
+	if setting, ok := record.(QorSEOSettingInterface); ok {
+		if collection := collectionOf(qorAdmin, setting.GetName()); collection != nil {
+			return collection.GetSEO(setting.GetName())
+		}
+		return nil
+	}
+
+	if typ := reflect.Indirect(reflect.ValueOf(record)).Type(); typ.Kind() == reflect.Struct {
+		if field, ok := typ.FieldByName(fieldName); ok {
+			if name := parseSEOTag(field.Tag.Get("seo"))["type"]; name != "" {
+				if collection := collectionOf(qorAdmin, name); collection != nil {
+					return collection.GetSEO(name)
+				}
+			}
+		}
+	}
+
+	if getter, ok := record.(seoGetter); ok {
+		return getter.GetSEO()
+	}
+	return nil
+}
+
+// collectionOf return the collection of admin that registered the seo name
+func collectionOf(qorAdmin *admin.Admin, name string) *Collection {
+	for _, collection := range collectionsOf(qorAdmin) {
+		if collection.isRegistered(name) {
+			return collection
+		}
+	}
+	return nil
+}
+
+// collectionsOf return collections added to admin
+func collectionsOf(qorAdmin *admin.Admin) (collections []*Collection) {
+	for _, res := range qorAdmin.GetResources() {
+		if collection, ok := res.Value.(*Collection); ok {
+			collections = append(collections, collection)
+		}
+	}
+	return
+}
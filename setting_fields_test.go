@@ -0,0 +1,93 @@
+package seo
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+type TaggedCategory struct {
+	gorm.Model
+	Name       string
+	DefaultSEO Setting `seo:"type:DefaultPage"`
+	SEO        Setting `seo:"type:CategoryPage"`
+}
+
+type LegacyCategory struct {
+	CurrentSeoField string
+	DefaultSEO      Setting
+	SEO             Setting
+}
+
+func TestSettingFields(t *testing.T) {
+	setupSeoCollection()
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} Category"})
+	context := &qor.Context{DB: db}
+
+	category := TaggedCategory{
+		DefaultSEO: Setting{Title: "Default Customized", EnabledCustomize: true},
+		SEO:        Setting{Title: "Category Customized", EnabledCustomize: true},
+	}
+
+	if setting := collection.GetSEOSetting(context, "CategoryPage", category); setting.Title != "Category Customized" {
+		t.Errorf("should use the field tagged with CategoryPage, but got %v", setting.Title)
+	}
+
+	if setting := collection.GetSEOSetting(context, "DefaultPage", &category); setting.Title != "Default Customized" {
+		t.Errorf("should use the field tagged with DefaultPage, but got %v", setting.Title)
+	}
+
+	legacy := LegacyCategory{
+		CurrentSeoField: "SEO",
+		DefaultSEO:      Setting{Title: "Default Customized", EnabledCustomize: true},
+		SEO:             Setting{Title: "Category Customized", EnabledCustomize: true},
+	}
+	if setting := collection.GetSEOSetting(context, "CategoryPage", legacy); setting.Title != "Category Customized" {
+		t.Errorf("should still support CurrentSeoField, but got %v", setting.Title)
+	}
+
+	legacy.CurrentSeoField = ""
+	if setting := collection.GetSEOSetting(context, "CategoryPage", legacy); setting.Title != "Default Customized" {
+		t.Errorf("should use the first field without tag, but got %v", setting.Title)
+	}
+
+	adminContext := &admin.Context{Context: context, Admin: Admin}
+	if seo := seoOfMeta(adminContext, &category, &admin.Meta{Name: "SEO", FieldName: "SEO"}); seo == nil || seo.Name != "CategoryPage" {
+		t.Errorf("seo of meta should be resolved from tag, but got %#v", seo)
+	}
+
+	if seo := seoOfMeta(adminContext, &category, &admin.Meta{Name: "DefaultSEO"}); seo == nil || seo.Name != "DefaultPage" {
+		t.Errorf("seo of meta should be resolved from tag, but got %#v", seo)
+	}
+}
+
+func TestAuditTaggedFields(t *testing.T) {
+	setupSeoCollection()
+	db.DropTableIfExists(&TaggedCategory{})
+	db.AutoMigrate(&TaggedCategory{})
+	Admin.AddResource(&TaggedCategory{})
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} Category"})
+
+	db.Create(&TaggedCategory{Name: "Shoes", DefaultSEO: Setting{EnabledCustomize: true}})
+
+	report, err := collection.Audit(&qor.Context{DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Records != 1 {
+		t.Errorf("should audit records with tagged fields, but got %v", report.Records)
+	}
+
+	if issues := report.IssuesByType(AuditEmptyCustomization); len(issues) != 1 || issues[0].SEOName != "DefaultPage" {
+		t.Errorf("should audit each tagged field with its seo, but got %#v", issues)
+	}
+
+	if issues := report.IssuesByType(AuditEmptyTitle); len(issues) != 1 || issues[0].SEOName != "DefaultPage" {
+		t.Errorf("should resolve each tagged field with its seo, but got %#v", issues)
+	}
+}
@@ -0,0 +1,54 @@
+package seo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SiteVerification verification tokens of webmaster tools, embed it into the site-wide setting struct to edit them in the Site Verification section.
+// Tokens are the content of verification meta tags, rendered on every page, or pages of Collection.VerificationSEO
+type SiteVerification struct {
+	GoogleSiteVerification     string
+	BingSiteVerification       string
+	YandexVerification         string
+	PinterestVerification      string
+	FacebookDomainVerification string
+}
+
+// VerificationProvider a webmaster tool, its token is saved in Field of site-wide setting, and rendered as meta tag MetaName
+type VerificationProvider struct {
+	Name     string
+	Field    string
+	MetaName string
+	Format   *regexp.Regexp
+}
+
+// VerificationProviders webmaster tools of SiteVerification, tokens are validated with their formats when saved
+var VerificationProviders = []VerificationProvider{
+	{Name: "Google", Field: "GoogleSiteVerification", MetaName: "google-site-verification", Format: regexp.MustCompile(`^[A-Za-z0-9_-]{20,100}$`)},
+	{Name: "Bing", Field: "BingSiteVerification", MetaName: "msvalidate.01", Format: regexp.MustCompile(`^[0-9A-Fa-f]{32}$`)},
+	{Name: "Yandex", Field: "YandexVerification", MetaName: "yandex-verification", Format: regexp.MustCompile(`^[0-9a-f]{16}$`)},
+	{Name: "Pinterest", Field: "PinterestVerification", MetaName: "p:domain_verify", Format: regexp.MustCompile(`^[0-9a-f]{32}$`)},
+	{Name: "Facebook", Field: "FacebookDomainVerification", MetaName: "facebook-domain-verification", Format: regexp.MustCompile(`^[a-z0-9]{20,40}$`)},
+}
+
+// validate check the token matches format of the provider, blank token is valid
+func (provider VerificationProvider) validate(token string) error {
+	if token = strings.TrimSpace(token); token != "" && provider.Format != nil && !provider.Format.MatchString(token) {
+		return fmt.Errorf("%v verification token %v is invalid, it should be the content of meta tag %v", provider.Name, token, provider.MetaName)
+	}
+	return nil
+}
+
+// verificationMetadata return verification tags of site-wide values, for all seos if VerificationSEO is blank
+func (collection *Collection) verificationMetadata(seo *SEO, values map[string]string) (metadata OpenGraphProperties) {
+	if collection.VerificationSEO != "" && collection.VerificationSEO != seo.Name {
+		return nil
+	}
+
+	for _, provider := range VerificationProviders {
+		metadata = metadata.addValue(provider.MetaName, values[provider.Field])
+	}
+	return metadata
+}
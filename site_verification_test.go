@@ -0,0 +1,113 @@
+package seo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+type VerifiedGlobalSetting struct {
+	SiteName string
+	SiteVerification
+}
+
+func setupSiteVerification() {
+	setupSeoCollection()
+	collection = New("Seo")
+	collection.RegisterGlobalVaribles(&VerifiedGlobalSetting{})
+	collection.RegisterSEO(&SEO{Name: "HomePage"})
+	collection.RegisterSEO(&SEO{Name: "ProductPage"})
+	Admin = admin.New(&qor.Config{DB: db})
+	Admin.AddResource(collection, &admin.Config{Name: "SEO Setting"})
+	Admin.MountTo("/admin", http.NewServeMux())
+}
+
+func TestSiteVerification(t *testing.T) {
+	setupSiteVerification()
+	context := &qor.Context{DB: db}
+
+	db.Create(&QorSEOSetting{Name: "Seo", IsGlobalSEO: true, Setting: Setting{GlobalSetting: map[string]string{
+		"GoogleSiteVerification": "abcdefghijklmnopqrstuvwxyz_0123456789-ABCDE", "BingSiteVerification": "0123456789ABCDEF0123456789ABCDEF",
+	}}})
+
+	expected := strings.Join([]string{
+		`<meta property="google-site-verification" name="google-site-verification" content="abcdefghijklmnopqrstuvwxyz_0123456789-ABCDE">`,
+		`<meta property="msvalidate.01" name="msvalidate.01" content="0123456789ABCDEF0123456789ABCDEF">`,
+	}, "\n")
+	for _, name := range []string{"HomePage", "ProductPage"} {
+		if result := string(collection.Render(context, name)); !strings.Contains(result, expected) || strings.Contains(result, "yandex-verification") {
+			t.Errorf("verification tags should be rendered on every page, but got %v", result)
+		}
+	}
+
+	collection.VerificationSEO = "HomePage"
+	if result := string(collection.Render(context, "HomePage")); !strings.Contains(result, expected) {
+		t.Errorf("verification tags should be rendered on the verification seo, but got %v", result)
+	}
+
+	if result := string(collection.Render(context, "ProductPage")); strings.Contains(result, "verification") {
+		t.Errorf("verification tags should not be rendered on other pages, but got %v", result)
+	}
+}
+
+func TestValidateSiteVerification(t *testing.T) {
+	testCases := []struct {
+		Field string
+		Token string
+		Valid bool
+	}{
+		{Field: "GoogleSiteVerification", Token: "abcdefghijklmnopqrstuvwxyz_0123456789-ABCDE", Valid: true},
+		{Field: "GoogleSiteVerification", Token: `<meta name="google-site-verification" content="abc">`, Valid: false},
+		{Field: "BingSiteVerification", Token: "0123456789ABCDEF", Valid: false},
+		{Field: "YandexVerification", Token: "0123456789abcdef", Valid: true},
+		{Field: "PinterestVerification", Token: "0123456789abcdef0123456789abcdef", Valid: true},
+		{Field: "FacebookDomainVerification", Token: "abcdefghijklmnopqrstuvwxyz0123", Valid: true},
+		{Field: "FacebookDomainVerification", Token: "ABC", Valid: false},
+		{Field: "YandexVerification", Token: "", Valid: true},
+	}
+
+	for i, testCase := range testCases {
+		for _, provider := range VerificationProviders {
+			if provider.Field == testCase.Field {
+				if err := provider.validate(testCase.Token); (err == nil) != testCase.Valid {
+					t.Errorf("#%v: %v should be valid: %v, but got %v", i+1, testCase.Token, testCase.Valid, err)
+				}
+			}
+		}
+	}
+
+	setupSiteVerification()
+	if errs := collection.validateImportedSetting(ExportedSetting{Name: "Seo", IsGlobalSEO: true, Setting: Setting{GlobalSetting: map[string]string{"BingSiteVerification": "invalid"}}}); len(errs) != 1 {
+		t.Errorf("imported verification tokens should be validated, but got %v", errs)
+	}
+}
+
+func TestSaveSiteVerification(t *testing.T) {
+	setupSiteVerification()
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	save := func(token string) map[string]string {
+		form := url.Values{"_method": {"PUT"}, "QorResource.SiteName": {"Qor"}, "QorResource.YandexVerification": {token}}
+		if _, err := http.PostForm(server.URL+collection.SEOSettingURL("Seo"), form); err != nil {
+			t.Fatal(err)
+		}
+
+		var setting QorSEOSetting
+		db.First(&setting, "name = ?", "Seo")
+		return setting.Setting.GlobalSetting
+	}
+
+	if values := save("0123456789abcdef"); values["YandexVerification"] != "0123456789abcdef" {
+		t.Errorf("valid verification token should be saved, but got %v", values)
+	}
+
+	if values := save("yandex"); values["YandexVerification"] != "0123456789abcdef" {
+		t.Errorf("invalid verification token should not be saved, but got %v", values)
+	}
+}
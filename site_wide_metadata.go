@@ -0,0 +1,120 @@
+package seo
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+// siteWideSection a struct embedded into site-wide setting struct, its fields are edited in their own section
+type siteWideSection struct {
+	Type  reflect.Type
+	Title string
+	Rows  [][]string
+}
+
+var siteWideSections = []siteWideSection{
+	{
+		Type:  reflect.TypeOf(AppLinks{}),
+		Title: "App Links",
+		Rows:  [][]string{{"IOSAppStoreID", "IOSAppName"}, {"AndroidPackage", "AndroidAppName"}},
+	},
+	{
+		Type:  reflect.TypeOf(SiteVerification{}),
+		Title: "Site Verification",
+		Rows:  [][]string{{"GoogleSiteVerification"}, {"BingSiteVerification"}, {"YandexVerification"}, {"PinterestVerification"}, {"FacebookDomainVerification"}},
+	},
+}
+
+// globalSettingStructFields return fields of site-wide setting struct, fields of embedded structs like AppLinks are included
+func globalSettingStructFields(typ reflect.Type) (fields []reflect.StructField) {
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.Anonymous && field.IsExported() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// configureSiteWideSections group fields of structs embedded in site-wide setting struct into sections, e.g: AppLinks, SiteVerification
+func configureSiteWideSections(res *admin.Resource) {
+	typ := reflect.Indirect(reflect.ValueOf(res.Value)).Type()
+
+	var sections []interface{}
+	for _, section := range siteWideSections {
+		if field, ok := typ.FieldByName(section.Type.Name()); ok && field.Anonymous && field.Type == section.Type {
+			sections = append(sections, &admin.Section{Title: section.Title, Rows: section.Rows})
+		}
+	}
+
+	if len(sections) == 0 {
+		return
+	}
+
+	var basicFields []string
+	for _, field := range globalSettingStructFields(typ) {
+		if len(field.Index) == 1 {
+			basicFields = append(basicFields, field.Name)
+		}
+	}
+	res.NewAttrs(append([]interface{}{&admin.Section{Rows: [][]string{basicFields}}}, sections...)...)
+}
+
+// siteWideTag a field of site-wide setting rendered as a tag, e.g: `seo:"tag:og:site_name"`
+type siteWideTag struct {
+	Field    string
+	Property string
+}
+
+// siteWideTags return fields of site-wide setting that are rendered as tags, in order of fields
+func (collection *Collection) siteWideTags() (tags []siteWideTag) {
+	if collection.globalSetting == nil {
+		return nil
+	}
+
+	typ := reflect.Indirect(reflect.ValueOf(collection.globalSetting)).Type()
+	for _, field := range globalSettingStructFields(typ) {
+		if property := parseSEOTag(field.Tag.Get("seo"))["tag"]; property != "" {
+			tags = append(tags, siteWideTag{Field: field.Name, Property: property})
+		}
+	}
+	return tags
+}
+
+// siteWideMetadata return tags mapped from site-wide setting, e.g: og:site_name, fb:app_id, twitter:site,
+// with og:locale and og:locale:alternate of locales the page is available in
+func (collection *Collection) siteWideMetadata(context *qor.Context, seo *SEO, values map[string]string, objects ...interface{}) (metadata OpenGraphProperties) {
+	for _, tag := range collection.siteWideTags() {
+		if value := values[tag.Field]; value != "" {
+			if tag.Property == "og:locale" {
+				value = openGraphLocale(value)
+			}
+			metadata = append(metadata, OpenGraphMetadata{Property: tag.Property, Content: value})
+		}
+	}
+
+	if seo.Locales == nil {
+		return metadata
+	}
+
+	current, available := seo.Locales(context, objects...)
+	if current = openGraphLocale(current); current != "" {
+		metadata = metadata.Merge([]OpenGraphMetadata{{Property: "og:locale", Content: current}})
+	} else {
+		current = metadata.Get("og:locale")
+	}
+
+	for _, locale := range available {
+		if locale = openGraphLocale(locale); locale != "" && locale != current {
+			metadata = append(metadata, OpenGraphMetadata{Property: "og:locale:alternate", Content: locale})
+		}
+	}
+	return metadata
+}
+
+// openGraphLocale format locale like en_US, which is used by open graph
+func openGraphLocale(locale string) string {
+	return strings.Replace(strings.TrimSpace(locale), "-", "_", -1)
+}
@@ -0,0 +1,72 @@
+package seo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qor/qor"
+)
+
+type TaggedGlobalSetting struct {
+	SiteName      string `seo:"tag:og:site_name"`
+	Locale        string `seo:"tag:og:locale"`
+	FacebookAppID string `seo:"tag:fb:app_id"`
+	TwitterSite   string `seo:"tag:twitter:site"`
+	BrandName     string
+}
+
+func setupSiteWideTags() {
+	setupSeoCollection()
+	collection.RegisterGlobalVaribles(&TaggedGlobalSetting{})
+	db.Create(&QorSEOSetting{Name: "Seo", IsGlobalSEO: true, Setting: Setting{GlobalSetting: map[string]string{
+		"SiteName": "Qor", "Locale": "en-US", "FacebookAppID": "1234", "TwitterSite": "@qor", "BrandName": "Qor Brand",
+	}}})
+}
+
+func TestSiteWideTags(t *testing.T) {
+	setupSiteWideTags()
+	context := &qor.Context{DB: db}
+
+	db.Create(&QorSEOSetting{Name: "DefaultPage", Setting: Setting{Title: "{{SiteName}}"}})
+	result := string(collection.Render(context, "DefaultPage"))
+	expected := strings.Join([]string{
+		`<meta property="og:site_name" name="og:site_name" content="Qor">`,
+		`<meta property="og:locale" name="og:locale" content="en_US">`,
+		`<meta property="fb:app_id" name="fb:app_id" content="1234">`,
+		`<meta property="twitter:site" name="twitter:site" content="@qor">`,
+	}, "\n")
+	if !strings.Contains(result, expected) || strings.Contains(result, "Qor Brand") {
+		t.Errorf("tagged site-wide fields should be rendered, but got %v", result)
+	}
+
+	db.Save(&QorSEOSetting{Name: "DefaultPage", Setting: Setting{Title: "{{SiteName}}", OpenGraphMetadata: []OpenGraphMetadata{{Property: "og:site_name", Content: "Qor Outlet"}}}})
+	if result := string(collection.Render(context, "DefaultPage")); strings.Count(result, "og:site_name") != 2 || !strings.Contains(result, `content="Qor Outlet"`) {
+		t.Errorf("site-wide tags should be replaced by metadata of the setting, but got %v", result)
+	}
+}
+
+func TestLocaleAlternates(t *testing.T) {
+	setupSiteWideTags()
+	collection.RegisterSEO(&SEO{
+		Name: "ProductPage",
+		Locales: func(context *qor.Context, objects ...interface{}) (string, []string) {
+			return objects[0].(string), []string{"en-US", "ja-JP", "zh-CN"}
+		},
+	})
+	context := &qor.Context{DB: db}
+
+	result := string(collection.Render(context, "ProductPage", "ja-JP"))
+	expected := strings.Join([]string{
+		`<meta property="og:locale" name="og:locale" content="ja_JP">`,
+		`<meta property="og:locale:alternate" name="og:locale:alternate" content="en_US">`,
+		`<meta property="og:locale:alternate" name="og:locale:alternate" content="zh_CN">`,
+	}, "\n")
+	if !strings.Contains(result, expected) || strings.Count(result, `"og:locale"`) != 2 {
+		t.Errorf("locale of the page should be rendered with alternates, but got %v", result)
+	}
+
+	result = string(collection.Render(context, "ProductPage", ""))
+	if !strings.Contains(result, `og:locale" content="en_US"`) || strings.Contains(result, `og:locale:alternate" name="og:locale:alternate" content="en_US"`) {
+		t.Errorf("site-wide locale should be used if page's locale is blank, but got %v", result)
+	}
+}
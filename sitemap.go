@@ -0,0 +1,121 @@
+package seo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/qor/qor"
+)
+
+// SitemapURL an url in sitemaps
+type SitemapURL struct {
+	Loc        string     `xml:"loc"`
+	LastMod    *time.Time `xml:"lastmod,omitempty"`
+	ChangeFreq string     `xml:"changefreq,omitempty"`
+	Priority   float64    `xml:"priority,omitempty"`
+}
+
+// MaxSitemapURLs max count of urls in a sitemap file, more urls will be split into multiple files with a sitemap index
+const MaxSitemapURLs = 50000
+
+// SitemapURLs return urls from sitemap sources of registered seos
+func (collection *Collection) SitemapURLs(context *qor.Context) (urls []SitemapURL, err error) {
+	for _, seo := range collection.registeredSEO {
+		if seo.Sitemap == nil {
+			continue
+		}
+
+		seoURLs, err := seo.Sitemap(context)
+		if err != nil {
+			return nil, fmt.Errorf("seo: failed to get sitemap urls of %v: %v", seo.Name, err)
+		}
+		urls = append(urls, seoURLs...)
+	}
+	return urls, nil
+}
+
+// WriteSitemaps generate sitemaps of registered seos into dir, relative urls are prefixed with baseURL.
+// It writes sitemap.xml, or a sitemap index named sitemap.xml with sitemap-1.xml, sitemap-2.xml... if there are too many urls
+func (collection *Collection) WriteSitemaps(context *qor.Context, dir string, baseURL string) (files []string, err error) {
+	urls, err := collection.SitemapURLs(context)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	for idx, u := range urls {
+		if !isAbsoluteURL(u.Loc) {
+			urls[idx].Loc = baseURL + "/" + strings.TrimPrefix(u.Loc, "/")
+		}
+	}
+
+	writeFile := func(name string, write func(io.Writer) error) error {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		files = append(files, file.Name())
+		return write(file)
+	}
+
+	if len(urls) <= MaxSitemapURLs {
+		return files, writeFile("sitemap.xml", func(w io.Writer) error { return WriteSitemap(w, urls) })
+	}
+
+	var locs []string
+	for i := 0; i*MaxSitemapURLs < len(urls); i++ {
+		end := (i + 1) * MaxSitemapURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		name := fmt.Sprintf("sitemap-%v.xml", i+1)
+		if err := writeFile(name, func(w io.Writer) error { return WriteSitemap(w, urls[i*MaxSitemapURLs:end]) }); err != nil {
+			return files, err
+		}
+		locs = append(locs, baseURL+"/"+name)
+	}
+
+	return files, writeFile("sitemap.xml", func(w io.Writer) error { return WriteSitemapIndex(w, locs) })
+}
+
+// WriteSitemap write urls as a sitemap
+func WriteSitemap(w io.Writer, urls []SitemapURL) error {
+	return writeSitemapXML(w, struct {
+		XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+		URLs    []SitemapURL `xml:"url"`
+	}{URLs: urls})
+}
+
+// WriteSitemapIndex write a sitemap index of sitemap locations
+func WriteSitemapIndex(w io.Writer, locs []string) error {
+	type sitemap struct {
+		Loc string `xml:"loc"`
+	}
+
+	index := struct {
+		XMLName  xml.Name  `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+		Sitemaps []sitemap `xml:"sitemap"`
+	}{}
+	for _, loc := range locs {
+		index.Sitemaps = append(index.Sitemaps, sitemap{Loc: loc})
+	}
+	return writeSitemapXML(w, index)
+}
+
+func writeSitemapXML(w io.Writer, value interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(value)
+}
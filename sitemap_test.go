@@ -0,0 +1,75 @@
+package seo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qor/qor"
+)
+
+func TestWriteSitemaps(t *testing.T) {
+	lastMod := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	collection := New("Seo")
+	collection.RegisterSEO(&SEO{Name: "HomePage", Sitemap: func(*qor.Context) ([]SitemapURL, error) {
+		return []SitemapURL{{Loc: "/", Priority: 1}}, nil
+	}})
+	collection.RegisterSEO(&SEO{Name: "ProductPage", Sitemap: func(*qor.Context) ([]SitemapURL, error) {
+		return []SitemapURL{{Loc: "products/1", LastMod: &lastMod, ChangeFreq: "weekly"}, {Loc: "https://cdn.example.com/products/2"}}, nil
+	}})
+	collection.RegisterSEO(&SEO{Name: "CategoryPage"})
+
+	dir := t.TempDir()
+	files, err := collection.WriteSitemaps(&qor.Context{}, dir, "https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+	expects := []string{
+		"<loc>https://example.com/</loc>",
+		"<priority>1</priority>",
+		"<loc>https://example.com/products/1</loc>",
+		"<lastmod>2024-01-02T03:04:05Z</lastmod>",
+		"<changefreq>weekly</changefreq>",
+		"<loc>https://cdn.example.com/products/2</loc>",
+	}
+	for _, expect := range expects {
+		if !strings.Contains(string(content), expect) {
+			t.Errorf("sitemap should contains %v, but got %v", expect, string(content))
+		}
+	}
+
+	if len(files) != 1 {
+		t.Errorf("should write one sitemap, but got %v", files)
+	}
+
+	collection.RegisterSEO(&SEO{Name: "TagPage", Sitemap: func(*qor.Context) ([]SitemapURL, error) {
+		var urls []SitemapURL
+		for i := 0; i < MaxSitemapURLs; i++ {
+			urls = append(urls, SitemapURL{Loc: fmt.Sprintf("/tags/%v", i)})
+		}
+		return urls, nil
+	}})
+
+	if files, err = collection.WriteSitemaps(&qor.Context{}, dir, "https://example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ = os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+	if len(files) != 3 || !strings.Contains(string(content), "<sitemapindex") || !strings.Contains(string(content), "<loc>https://example.com/sitemap-2.xml</loc>") {
+		t.Errorf("should split urls into multiple sitemaps with an index, but got %v %v", files, string(content))
+	}
+
+	collection.RegisterSEO(&SEO{Name: "BrokenPage", Sitemap: func(*qor.Context) ([]SitemapURL, error) {
+		return nil, errors.New("broken")
+	}})
+
+	if _, err = collection.WriteSitemaps(&qor.Context{}, dir, "https://example.com"); err == nil || !strings.Contains(err.Error(), "BrokenPage") {
+		t.Errorf("should return error of sitemap sources, but got %v", err)
+	}
+}
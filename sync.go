@@ -0,0 +1,53 @@
+package seo
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// SyncReport result of Collection.Sync
+type SyncReport struct {
+	// Created names of settings created for registered seos and the site-wide setting
+	Created []string
+	// Orphaned names of stored settings whose seo is not registered anymore
+	Orphaned []string
+}
+
+// Sync create missing settings of registered seos and the site-wide setting, and report orphaned settings.
+// It is idempotent, run it at startup or migration time. Settings of other collections sharing the table are reported as orphaned, unless they are added to the same admin
+func (collection *Collection) Sync(db *gorm.DB) (SyncReport, error) {
+	var report SyncReport
+
+	for _, name := range collection.settingNames() {
+		// deleted settings are not created again
+		err := db.Unscoped().Where("name = ?", name).First(collection.SettingResource.NewStruct()).Error
+		if err == nil {
+			continue
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return report, err
+		}
+
+		if err := db.Create(collection.newSetting(name)).Error; err != nil {
+			// created by a concurrent Sync
+			if db.Unscoped().Where("name = ?", name).First(collection.SettingResource.NewStruct()).Error == nil {
+				continue
+			}
+			return report, err
+		}
+		report.Created = append(report.Created, name)
+	}
+
+	var err error
+	report.Orphaned, err = collection.OrphanedSettings(db)
+	return report, err
+}
+
+// newSetting initialize an unsaved setting of seo name, it is the site-wide setting if name is name of the collection
+func (collection *Collection) newSetting(name string) QorSEOSettingInterface {
+	setting := collection.SettingResource.NewStruct().(QorSEOSettingInterface)
+	setting.SetName(name)
+	setting.SetSEOType(name)
+	if name == collection.Name {
+		setting.SetIsGlobalSEO(true)
+	}
+	return setting
+}
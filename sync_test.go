@@ -0,0 +1,67 @@
+package seo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	setupSeoCollection()
+	db.Create(&QorSEOSetting{Name: "RemovedPage", Setting: Setting{Title: "Removed"}})
+
+	report, err := collection.Sync(db)
+	if err != nil {
+		t.Fatalf("failed to sync settings, got %v", err)
+	}
+
+	if !reflect.DeepEqual(report.Created, []string{"Seo", "DefaultPage", "CategoryPage"}) || !reflect.DeepEqual(report.Orphaned, []string{"RemovedPage"}) {
+		t.Errorf("should create missing settings and report orphaned ones, but got %#v", report)
+	}
+
+	var global QorSEOSetting
+	if db.First(&global, "name = ?", "Seo"); !global.IsGlobalSEO || global.Setting.Type != "Seo" {
+		t.Errorf("should create site-wide setting, but got %#v", global)
+	}
+
+	db.Model(&QorSEOSetting{}).Where("name = ?", "CategoryPage").Update("setting", Setting{Title: "Category"})
+	if report, err = collection.Sync(db); err != nil || len(report.Created) != 0 {
+		t.Errorf("sync should be idempotent, but got %#v, %v", report, err)
+	}
+
+	var setting QorSEOSetting
+	if db.First(&setting, "name = ?", "CategoryPage"); setting.Setting.Title != "Category" {
+		t.Errorf("sync should not change existing settings, but got %#v", setting)
+	}
+
+	var count int
+	if db.Model(&QorSEOSetting{}).Count(&count); count != 4 {
+		t.Errorf("should have 4 settings, but got %v", count)
+	}
+}
+
+func TestSaveSettingsNotSynced(t *testing.T) {
+	setupSeoCollection()
+	server := httptest.NewServer(Admin.NewServeMux("/admin"))
+	defer server.Close()
+
+	if _, err := http.PostForm(server.URL+collection.SEOSettingURL("Seo"), url.Values{"_method": {"PUT"}, "QorResource.SiteName": {"Qor"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var global QorSEOSetting
+	if db.First(&global, "name = ?", "Seo"); !global.IsGlobalSEO || global.GetGlobalSetting()["SiteName"] != "Qor" {
+		t.Errorf("should create site-wide setting when it is saved, but got %#v", global)
+	}
+
+	if _, err := http.PostForm(server.URL+collection.SEOSettingURL("CategoryPage"), url.Values{"_method": {"PUT"}, "QorResource.Setting.Title": {"{{SiteName}}"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var setting QorSEOSetting
+	if db.First(&setting, "name = ?", "CategoryPage"); setting.Setting.Title != "{{SiteName}}" || setting.Setting.Type != "CategoryPage" || setting.IsGlobalSEO {
+		t.Errorf("should create setting when it is saved, but got %#v", setting)
+	}
+}
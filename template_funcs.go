@@ -0,0 +1,117 @@
+package seo
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"reflect"
+
+	"github.com/qor/qor"
+)
+
+// FuncMap return template helpers for front-end templates, helpers find *qor.Context from template data,
+// which could be the *qor.Context, or a struct or map that has a *qor.Context in its fields or values, e.g:
+//
+//	tmpl := template.New("layout").Funcs(SeoCollection.FuncMap())
+//	tmpl.Execute(w, map[string]interface{}{"Context": &qor.Context{DB: db, Request: req}, "Product": product})
+//
+//	<head>
+//	  {{seo_render . "Product Page" .Product}}
+//	  <link rel="canonical" href="{{seo_canonical . "Product Page" .Product}}">
+//	  {{seo_jsonld .Product.MicroProduct}}
+//	</head>
+//	<h1>{{seo_title . "Product Page" .Product}}</h1>
+func (collection *Collection) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"seo_render": func(data interface{}, name string, objects ...interface{}) (template.HTML, error) {
+			context, err := qorContextFrom(data)
+			if err != nil {
+				return "", err
+			}
+			return collection.Render(context, name, objects...), nil
+		},
+		"seo_title": func(data interface{}, name string, objects ...interface{}) (string, error) {
+			context, err := qorContextFrom(data)
+			if err != nil {
+				return "", err
+			}
+			return collection.GetSEOSetting(context, name, objects...).Title, nil
+		},
+		"seo_canonical": func(data interface{}, name string, objects ...interface{}) (string, error) {
+			context, err := qorContextFrom(data)
+			if err != nil {
+				return "", err
+			}
+			return canonicalURL(context, collection.GetSEOSetting(context, name, objects...)), nil
+		},
+		"seo_jsonld": renderJSONLD,
+	}
+}
+
+// canonicalURL return absolute og:url of the setting, or url of current request without query
+func canonicalURL(context *qor.Context, setting Setting) string {
+	if setting.OpenGraphURL != "" {
+		return absoluteURL(context, setting.OpenGraphURL)
+	}
+
+	if context.Request != nil && context.Request.URL != nil {
+		return absoluteURL(context, context.Request.URL.Path)
+	}
+	return ""
+}
+
+// renderJSONLD render structured data like MicroProduct with their templates, other values are encoded as JSON-LD scripts
+func renderJSONLD(values ...interface{}) (template.HTML, error) {
+	var result template.HTML
+	for _, value := range values {
+		if microData, ok := value.(interface{ Render() template.HTML }); ok {
+			result += microData.Render()
+			continue
+		}
+
+		// json.Marshal escapes <, > and &, so the script can't be closed by values
+		content, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		result += template.HTML(`<script type="application/ld+json">` + string(content) + `</script>`)
+	}
+	return result, nil
+}
+
+var qorContextType = reflect.TypeOf(&qor.Context{})
+
+// qorContextFrom find *qor.Context from template data, embedded structs like *admin.Context are searched too
+func qorContextFrom(data interface{}) (*qor.Context, error) {
+	if context, ok := data.(*qor.Context); ok && context != nil {
+		return context, nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(data))
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Field(i)
+			if !field.CanInterface() || (field.Kind() == reflect.Ptr && field.IsNil()) {
+				continue
+			}
+
+			if field.Type() == qorContextType {
+				return field.Interface().(*qor.Context), nil
+			}
+
+			if value.Type().Field(i).Anonymous {
+				if context, err := qorContextFrom(field.Interface()); err == nil {
+					return context, nil
+				}
+			}
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			if context, ok := value.MapIndex(key).Interface().(*qor.Context); ok && context != nil {
+				return context, nil
+			}
+		}
+	}
+	return nil, errors.New("seo: *qor.Context is not found in template data")
+}
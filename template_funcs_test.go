@@ -0,0 +1,65 @@
+package seo
+
+import (
+	"bytes"
+	"html/template"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qor/admin"
+	"github.com/qor/qor"
+)
+
+func TestFuncMap(t *testing.T) {
+	setupSeoCollection()
+	createGlobalSetting("Qor")
+	createCategoryPageSetting(Setting{Title: "{{SiteName}} - {{Name}}", Description: "Shop {{Name}}"})
+
+	tmpl := template.Must(template.New("layout").Funcs(collection.FuncMap()).Parse(
+		`<head>{{seo_render . "CategoryPage" .Name}}<link rel="canonical" href="{{seo_canonical . "CategoryPage" .Name}}">{{seo_jsonld .Product .Breadcrumb}}</head><h1>{{seo_title . "CategoryPage" .Name}}</h1>`,
+	))
+
+	context := &qor.Context{DB: db, Request: httptest.NewRequest("GET", "http://qor.io/categories/shoes?page=2", nil)}
+	data := map[string]interface{}{
+		"Context":    context,
+		"Name":       "Shoes",
+		"Product":    MicroProduct{Name: "Polo"},
+		"Breadcrumb": map[string]string{"@type": "BreadcrumbList", "name": "</script>"},
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("failed to execute template, got %v", err)
+	}
+
+	for _, expect := range []string{
+		"<title>Qor - Shoes</title>", `content="Shop Shoes"`, "<h1>Qor - Shoes</h1>",
+		`<link rel="canonical" href="http://qor.io/categories/shoes">`,
+		`<span itemprop="name">Polo</span>`,
+		`<script type="application/ld+json">{"@type":"BreadcrumbList","name":"\u003c/script\u003e"}</script>`,
+	} {
+		if !strings.Contains(buf.String(), expect) {
+			t.Errorf("template should contains %v, but got %v", expect, buf.String())
+		}
+	}
+
+	createCategoryPageSetting(Setting{Title: "{{Name}}", OpenGraphURL: "/c/{{Name}}"})
+	buf.Reset()
+	if err := tmpl.Execute(&buf, struct {
+		*admin.Context
+		Name       string
+		Product    interface{}
+		Breadcrumb interface{}
+	}{Context: &admin.Context{Context: context}, Name: "hats"}); err != nil {
+		t.Fatalf("failed to execute template with embedded context, got %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `<link rel="canonical" href="http://qor.io/c/hats">`) {
+		t.Errorf("canonical url should use og:url, but got %v", buf.String())
+	}
+
+	if err := tmpl.Execute(&bytes.Buffer{}, map[string]interface{}{"Name": "Shoes"}); err == nil {
+		t.Errorf("should return error if no *qor.Context in template data")
+	}
+}